@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	libraw "github.com/stmtc233/go-libraw"
+)
+
+// config is the result of parsing the command line.
+type config struct {
+	opts      libraw.ProcessorOptions
+	files     []string
+	suffix    string // output file extension, selects the format
+	stdout    bool
+	thumbnail bool
+	allShots  bool
+	quality   int
+	verbose   bool
+}
+
+// option is a dcraw style flag: a fixed number of arguments follow it.
+type option struct {
+	args  int
+	usage string
+	apply func(c *config, args []string) error
+}
+
+// options are the flags of dcraw and dcraw_emu, plus -preset, -profile
+// and -Q. Everything maps onto ProcessorOptions.
+var options = map[string]option{
+	"-v": {0, "Print verbose messages", func(c *config, _ []string) error {
+		c.verbose = true
+		return nil
+	}},
+	"-c": {0, "Write image data to standard output", func(c *config, _ []string) error {
+		c.stdout = true
+		return nil
+	}},
+	"-e": {0, "Extract embedded thumbnail image", func(c *config, _ []string) error {
+		c.thumbnail = true
+		return nil
+	}},
+	"-w": {0, "Use camera white balance, if possible", func(c *config, _ []string) error {
+		c.opts.UseCameraWb = true
+		return nil
+	}},
+	"-a": {0, "Average the whole image for white balance", func(c *config, _ []string) error {
+		c.opts.UseAutoWb = true
+		return nil
+	}},
+	"-A": {4, "<x y w h> Average a grey box for white balance", func(c *config, args []string) error {
+		return parseBox(&c.opts.Greybox, args)
+	}},
+	"-r": {4, "<r g b g> Set custom white balance", func(c *config, args []string) error {
+		for i, a := range args {
+			v, err := parseFloat(a, 0, math.MaxFloat32)
+			if err != nil {
+				return err
+			}
+			c.opts.UserMul[i] = float32(v)
+		}
+		return nil
+	}},
+	"-M": {0, "Use the embedded color matrix for all files", func(c *config, _ []string) error {
+		c.opts.UseCameraMatrix = libraw.CameraMatrixAlways
+		return nil
+	}},
+	"+M": {0, "Never use the embedded color matrix", func(c *config, _ []string) error {
+		c.opts.UseCameraMatrix = libraw.CameraMatrixNever
+		return nil
+	}},
+	"-C": {2, "<r b> Correct chromatic aberration", func(c *config, args []string) error {
+		r, err := parseFloat(args[0], math.SmallestNonzeroFloat64, math.MaxFloat64)
+		if err != nil {
+			return err
+		}
+		b, err := parseFloat(args[1], math.SmallestNonzeroFloat64, math.MaxFloat64)
+		if err != nil {
+			return err
+		}
+		c.opts.Aber[0], c.opts.Aber[2] = 1/r, 1/b
+		return nil
+	}},
+	"-P": {1, "<file> Fix the dead pixels listed in this file", func(c *config, args []string) error {
+		c.opts.BadPixels = args[0]
+		return nil
+	}},
+	"-K": {1, "<file> Subtract dark frame (16-bit raw PGM)", func(c *config, args []string) error {
+		c.opts.DarkFrame = args[0]
+		return nil
+	}},
+	"-k": {1, "<num> Set the darkness level", func(c *config, args []string) error {
+		return parseInt(&c.opts.UserBlack, args[0])
+	}},
+	"-S": {1, "<num> Set the saturation level", func(c *config, args []string) error {
+		return parseInt(&c.opts.UserSat, args[0])
+	}},
+	"-n": {1, "<num> Set threshold for wavelet denoising", func(c *config, args []string) error {
+		v, err := parseFloat(args[0], 0, math.MaxFloat32)
+		c.opts.Threshold = float32(v)
+		return err
+	}},
+	"-H": {1, "[0-9] Highlight mode (0=clip, 1=unclip, 2=blend, 3+=rebuild)", func(c *config, args []string) (err error) {
+		c.opts.Highlight, err = libraw.ParseHighlightMode(args[0])
+		return err
+	}},
+	"-t": {1, "[0-7] Flip image (0=none, 3=180, 5=90CCW, 6=90CW)", func(c *config, args []string) (err error) {
+		c.opts.UserFlip, err = libraw.ParseFlip(args[0])
+		return err
+	}},
+	"-o": {1, "[0-8] Output colorspace (raw,sRGB,Adobe,Wide,ProPhoto,XYZ,ACES,DCI-P3,Rec2020) or <file> output ICC profile", func(c *config, args []string) error {
+		if color, err := libraw.ParseOutputColor(args[0]); err == nil {
+			c.opts.OutputColor = color
+			return nil
+		}
+		if _, err := strconv.Atoi(args[0]); err == nil {
+			return fmt.Errorf("unknown output colorspace %s", args[0])
+		}
+		c.opts.OutputProfile = args[0]
+		return nil
+	}},
+	"-p": {1, "<file> Apply camera ICC profile (\"embed\" for the embedded one)", func(c *config, args []string) error {
+		c.opts.CameraProfile = args[0]
+		return nil
+	}},
+	"-j": {0, "Don't stretch or rotate raw pixels", func(c *config, _ []string) error {
+		c.opts.UseFujiRotate = false
+		return nil
+	}},
+	"-W": {0, "Don't automatically brighten the image", func(c *config, _ []string) error {
+		c.opts.NoAutoBright = true
+		return nil
+	}},
+	"-b": {1, "<num> Adjust brightness (default = 1.0)", func(c *config, args []string) error {
+		v, err := parseFloat(args[0], math.SmallestNonzeroFloat32, math.MaxFloat32)
+		c.opts.Bright = float32(v)
+		return err
+	}},
+	"-g": {2, "<p ts> Set custom gamma curve (default = 2.222 4.5)", func(c *config, args []string) error {
+		p, err := parseFloat(args[0], math.SmallestNonzeroFloat64, math.MaxFloat64)
+		if err != nil {
+			return err
+		}
+		ts, err := parseFloat(args[1], 0, math.MaxFloat64)
+		if err != nil {
+			return err
+		}
+		c.opts.Gamm = [6]float64{1 / p, ts}
+		return nil
+	}},
+	"-q": {1, "[0-3] Set the interpolation quality (also 4=DCB, 11=DHT, 12=AAHD)", func(c *config, args []string) (err error) {
+		c.opts.UserQual, err = libraw.ParseInterpolation(args[0])
+		return err
+	}},
+	"-h": {0, "Half-size color image", func(c *config, _ []string) error {
+		c.opts.HalfSize = true
+		return nil
+	}},
+	"-f": {0, "Interpolate RGGB as four colors", func(c *config, _ []string) error {
+		c.opts.FourColorRGB = true
+		return nil
+	}},
+	"-m": {1, "<num> Apply a 3x3 median filter to R-G and B-G", func(c *config, args []string) error {
+		return parseInt(&c.opts.MedPasses, args[0])
+	}},
+	"-s": {1, "[0..N-1] Select one raw image or \"all\" from each file", func(c *config, args []string) error {
+		if args[0] == "all" {
+			c.allShots = true
+			if c.opts.Unpack.IsZero() {
+				c.opts.Unpack = libraw.DefaultUnpackOptions()
+			}
+			return nil
+		}
+		n, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid shot %q", args[0])
+		}
+		if c.opts.Unpack.IsZero() {
+			c.opts.Unpack = libraw.DefaultUnpackOptions()
+		}
+		c.opts.Unpack.ShotSelect = uint(n)
+		return nil
+	}},
+	"-6": {0, "Write 16-bit instead of 8-bit", func(c *config, _ []string) error {
+		c.opts.OutputBps = 16
+		return nil
+	}},
+	"-4": {0, "Linear 16-bit, same as \"-6 -W -g 1 1\"", func(c *config, _ []string) error {
+		c.opts.OutputBps = 16
+		c.opts.NoAutoBright = true
+		c.opts.Gamm = [6]float64{1, 1}
+		return nil
+	}},
+	"-T": {0, "Write TIFF instead of PPM", func(c *config, _ []string) error {
+		c.suffix = ".tiff"
+		return nil
+	}},
+	"-Z": {1, "<suffix> Output file suffix, selects the format (.ppm, .tiff, .png, .jpg); \"-\" writes to standard output", func(c *config, args []string) error {
+		if args[0] == "-" {
+			c.stdout = true
+			return nil
+		}
+		c.suffix = args[0]
+		if !strings.HasPrefix(c.suffix, ".") {
+			c.suffix = "." + c.suffix
+		}
+		_, err := formatOf(c.suffix)
+		return err
+	}},
+	"-Q": {1, "<1-100> JPEG quality (default = 90)", func(c *config, args []string) error {
+		return parseInt(&c.quality, args[0])
+	}},
+	"-aexpo": {2, "<e p> Exposure correction: linear shift 0.25-8, highlight preservation 0-1", func(c *config, args []string) error {
+		shift, err := parseFloat(args[0], 0.25, 8)
+		if err != nil {
+			return err
+		}
+		preser, err := parseFloat(args[1], 0, 1)
+		if err != nil {
+			return err
+		}
+		c.opts.ExpCorrect, c.opts.ExpShift, c.opts.ExpPreser = true, float32(shift), float32(preser)
+		return nil
+	}},
+	"-dcbi": {1, "<num> Number of extra DCB iterations", func(c *config, args []string) error {
+		return parseInt(&c.opts.DcbIterations, args[0])
+	}},
+	"-dcbe": {0, "DCB color enhance", func(c *config, _ []string) error {
+		c.opts.DcbEnhanceFl = true
+		return nil
+	}},
+	"-fbdd": {1, "[0-2] FBDD noise reduction before demosaicing (0=off, 1=light, 2=full)", func(c *config, args []string) (err error) {
+		c.opts.FbddNoiserd, err = libraw.ParseFBDDNoiseReduction(args[0])
+		return err
+	}},
+	"-preset":  {1, "<name> Start from a registered preset (applied before all other flags)", nil},
+	"-profile": {1, "<file> Start from options in a JSON, YAML or TOML file (applied before all other flags)", nil},
+}
+
+// parseArgs parses a dcraw command line. Flags come before the files; like
+// dcraw, a flag's arguments are the words that follow it. -preset and
+// -profile set the starting options wherever they appear.
+func parseArgs(args []string) (*config, error) {
+	c := &config{opts: libraw.NewProcessorOptions(), suffix: ".ppm", quality: 90}
+
+	type setting struct {
+		opt  option
+		args []string
+	}
+	var settings []setting
+	i := 0
+	for ; i < len(args); i++ {
+		flag := args[i]
+		if flag == "--" {
+			i++
+			break
+		}
+		if len(flag) < 2 || (flag[0] != '-' && flag[0] != '+') {
+			break
+		}
+		opt, ok := options[flag]
+		if !ok {
+			return nil, fmt.Errorf("unknown option %q", flag)
+		}
+		if i+opt.args >= len(args) {
+			return nil, fmt.Errorf("%s needs %d argument(s)", flag, opt.args)
+		}
+		optArgs := args[i+1 : i+1+opt.args]
+		i += opt.args
+
+		switch flag {
+		case "-preset":
+			opts, ok := libraw.LookupPreset(optArgs[0])
+			if !ok {
+				return nil, fmt.Errorf("unknown preset %q, have %s", optArgs[0], strings.Join(libraw.Presets(), ", "))
+			}
+			c.opts = opts
+		case "-profile":
+			opts, err := libraw.LoadOptions(optArgs[0])
+			if err != nil {
+				return nil, err
+			}
+			c.opts = opts
+		default:
+			settings = append(settings, setting{opt, optArgs})
+		}
+	}
+
+	for _, s := range settings {
+		if err := s.opt.apply(c, s.args); err != nil {
+			return nil, err
+		}
+	}
+	c.files = args[i:]
+	return c, nil
+}
+
+// outputPath returns the file written for a RAW file: the input path with
+// its extension replaced, like dcraw.
+func (c *config) outputPath(path string, shot int, suffix string) string {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	if shot >= 0 {
+		base += fmt.Sprintf("_%d", shot)
+	}
+	return base + suffix
+}
+
+func parseBox(b *libraw.Box, args []string) error {
+	var v [4]uint
+	for i, a := range args {
+		n, err := strconv.ParseUint(a, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid box %s", strings.Join(args, " "))
+		}
+		v[i] = uint(n)
+	}
+	*b = libraw.Box{X1: v[0], Y1: v[1], X2: v[2], Y2: v[3]}
+	return nil
+}
+
+func parseInt(dst *int, s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*dst = n
+	return nil
+}
+
+func parseFloat(s string, lo, hi float64) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < lo || v > hi {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	libraw "github.com/stmtc233/go-libraw"
+)
+
+func TestParseArgs(t *testing.T) {
+	c, err := parseArgs([]string{
+		"-w", "-q", "3", "-H", "2", "-o", "4", "-6", "-T",
+		"-g", "2.4", "12.92", "-C", "1.001", "0.999", "-k", "512", "-S", "16000",
+		"-n", "100", "-b", "1.5", "-t", "6", "-r", "2", "1", "1.5", "1",
+		"a.CR2", "b.NEF",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := c.opts
+	r, b := 1.001, 0.999
+	switch {
+	case !o.UseCameraWb:
+		t.Error("-w not applied")
+	case o.UserQual != libraw.InterpolationAHD:
+		t.Errorf("-q 3 gave %v", o.UserQual)
+	case o.Highlight != libraw.HighlightBlend:
+		t.Errorf("-H 2 gave %v", o.Highlight)
+	case o.OutputColor != libraw.ProPhotoRGB:
+		t.Errorf("-o 4 gave %v", o.OutputColor)
+	case o.OutputBps != 16 || c.suffix != ".tiff":
+		t.Errorf("-6 -T gave %d bits, suffix %s", o.OutputBps, c.suffix)
+	case o.Gamm[0] != 1/2.4 || o.Gamm[1] != 12.92:
+		t.Errorf("-g gave %v", o.Gamm)
+	case o.Aber[0] != 1/r || o.Aber[2] != 1/b:
+		t.Errorf("-C gave %v", o.Aber)
+	case o.UserBlack != 512 || o.UserSat != 16000 || o.Threshold != 100 || o.Bright != 1.5:
+		t.Error("-k -S -n -b not applied")
+	case o.UserFlip != libraw.FlipRotate90:
+		t.Errorf("-t 6 gave %v", o.UserFlip)
+	case o.UserMul != [4]float32{2, 1, 1.5, 1}:
+		t.Errorf("-r gave %v", o.UserMul)
+	}
+	if !slices.Equal(c.files, []string{"a.CR2", "b.NEF"}) {
+		t.Errorf("files %v", c.files)
+	}
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseArgsLinear(t *testing.T) {
+	c, err := parseArgs([]string{"-4", "-Z", "png", "x.dng"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.opts.OutputBps != 16 || !c.opts.NoAutoBright || c.opts.Gamm != [6]float64{1, 1} {
+		t.Errorf("-4 gave %d bits, auto bright off %v, gamma %v", c.opts.OutputBps, c.opts.NoAutoBright, c.opts.Gamm)
+	}
+	if c.suffix != ".png" {
+		t.Errorf("-Z png gave suffix %s", c.suffix)
+	}
+	if got := c.outputPath("dir/x.dng", 2, c.suffix); got != "dir/x_2.png" {
+		t.Errorf("output path %s", got)
+	}
+}
+
+func TestParseArgsPreset(t *testing.T) {
+	// the preset is the base even when given after other flags
+	c, err := parseArgs([]string{"-h", "-preset", libraw.PresetArchival16, "f.ARW"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.opts.HalfSize || c.opts.OutputBps != 16 {
+		t.Errorf("half size %v, %d bits", c.opts.HalfSize, c.opts.OutputBps)
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"-x", "f.CR2"},
+		{"-q"},
+		{"-q", "fast", "f.CR2"},
+		{"-o", "9", "f.CR2"},
+		{"-g", "0", "4.5", "f.CR2"},
+		{"-Z", ".gif", "f.CR2"},
+		{"-preset", "nope", "f.CR2"},
+	} {
+		if _, err := parseArgs(args); err == nil {
+			t.Errorf("%q accepted", args)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Command goraw decodes RAW files like dcraw and LibRaw's dcraw_emu, with
+// the same flags, and writes PPM, TIFF, PNG or JPEG images.
+//
+//	goraw -w -T -6 IMG_0001.CR2   writes IMG_0001.tiff
+//	goraw -q 3 -Z .jpg *.NEF      writes a JPEG per file
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	libraw "github.com/stmtc233/go-libraw"
+	"github.com/stmtc233/go-libraw/pkg/export"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(1)
+	}
+	c, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goraw: %v\n", err)
+		os.Exit(1)
+	}
+	if len(c.files) == 0 {
+		usage(os.Stderr)
+		os.Exit(1)
+	}
+	if err := c.opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "goraw: %v\n", err)
+		os.Exit(1)
+	}
+
+	// like dcraw, carry on with the other files after an error
+	status := 0
+	for _, path := range c.files {
+		if err := c.run(path); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Raw photo decoder \"goraw\" using LibRaw %s\n", libraw.Version())
+	fmt.Fprintf(w, "Usage: %s [OPTION]... [FILE]...\n\n", os.Args[0])
+	flags := make([]string, 0, len(options))
+	for flag := range options {
+		flags = append(flags, flag)
+	}
+	slices.SortFunc(flags, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a[1:]), strings.ToLower(b[1:]))
+	})
+	for _, flag := range flags {
+		fmt.Fprintf(w, "%-8s %s\n", flag, options[flag].usage)
+	}
+}
+
+// run decodes one file and writes its output.
+func (c *config) run(path string) error {
+	p := libraw.NewProcessor(c.opts)
+	if c.thumbnail {
+		return c.writeThumbnail(p, path)
+	}
+	if !c.allShots {
+		return c.writeImage(p, path, -1)
+	}
+	n, err := p.FrameCount(path)
+	if err != nil {
+		return err
+	}
+	for i := range n {
+		opts := c.opts
+		opts.Unpack.ShotSelect = uint(i)
+		if err := c.writeImage(libraw.NewProcessor(opts), path, i); err != nil {
+			return fmt.Errorf("frame %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (c *config) writeImage(p *libraw.Processor, path string, shot int) error {
+	if c.verbose {
+		fmt.Fprintf(os.Stderr, "Processing %s ...\n", path)
+	}
+	r, err := p.Render(path)
+	if err != nil {
+		return err
+	}
+	format, err := formatOf(c.suffix)
+	if err != nil {
+		return err
+	}
+	return c.write(c.outputPath(path, shot, c.suffix), func(w io.Writer) error {
+		switch format {
+		case formatTIFF:
+			return r.EncodeTIFF(w, nil)
+		case formatPNG:
+			return r.EncodePNG(w)
+		case formatJPEG:
+			return r.EncodeJPEG(w, c.quality)
+		}
+		return export.EncodePNM(w, r.Image, c.opts.OutputBps)
+	})
+}
+
+// writeThumbnail writes the embedded preview as .thumb.jpg, or as
+// .thumb.ppm for bitmap previews, like dcraw -e.
+func (c *config) writeThumbnail(p *libraw.Processor, path string) error {
+	thumb, err := p.ExtractThumbnail(path)
+	if err != nil {
+		return err
+	}
+	switch thumb.Format {
+	case libraw.ThumbJpeg:
+		return c.write(c.outputPath(path, -1, ".thumb.jpg"), func(w io.Writer) error {
+			_, err := w.Write(thumb.Data)
+			return err
+		})
+	case libraw.ThumbBitmap:
+		magic := "P6"
+		if thumb.Colors == 1 {
+			magic = "P5"
+		}
+		return c.write(c.outputPath(path, -1, ".thumb.ppm"), func(w io.Writer) error {
+			fmt.Fprintf(w, "%s\n%d %d\n%d\n", magic, thumb.Width, thumb.Height, 1<<thumb.Bits-1)
+			_, err := w.Write(thumb.Data)
+			return err
+		})
+	}
+	return fmt.Errorf("unsupported thumbnail format %d", thumb.Format)
+}
+
+// write sends the output to name, or to standard output with -c.
+// write encodes to standard output with -Z, and otherwise to a temporary
+// file next to name that is renamed once complete, so a failed write
+// leaves no truncated output behind.
+func (c *config) write(name string, encode func(w io.Writer) error) error {
+	if c.stdout {
+		w := bufio.NewWriter(os.Stdout)
+		if err := encode(w); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+
+	if c.verbose {
+		fmt.Fprintf(os.Stderr, "Writing data to %s ...\n", name)
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = encode(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Chmod(0o644) // CreateTemp makes it private
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+type format int
+
+const (
+	formatPNM format = iota
+	formatTIFF
+	formatPNG
+	formatJPEG
+)
+
+// formatOf returns the output format selected by a file suffix.
+func formatOf(suffix string) (format, error) {
+	switch strings.ToLower(suffix[strings.LastIndex(suffix, ".")+1:]) {
+	case "ppm", "pgm", "pnm":
+		return formatPNM, nil
+	case "tif", "tiff":
+		return formatTIFF, nil
+	case "png":
+		return formatPNG, nil
+	case "jpg", "jpeg":
+		return formatJPEG, nil
+	}
+	return 0, fmt.Errorf("no output format for suffix %q", suffix)
+}
@@ -0,0 +1,220 @@
+// Command rawinfo prints what LibRaw knows about RAW files, in the layout
+// of LibRaw's raw-identify or as JSON.
+//
+//	rawinfo IMG_0001.CR2            one line per file, like raw-identify
+//	rawinfo -v shoot/               raw-identify -v for every RAW file below shoot
+//	rawinfo -json -u '*.NEF'        JSON, with the raw data unpacked
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	libraw "github.com/stmtc233/go-libraw"
+)
+
+// result is the JSON output for one file.
+type result struct {
+	File  string `json:"file"`
+	Error string `json:"error,omitempty"`
+	*libraw.FileInfo
+}
+
+func main() {
+	verbose := flag.Bool("v", false, "print everything, like raw-identify -v")
+	asJSON := flag.Bool("json", false, "print a JSON array with one object per file")
+	unpack := flag.Bool("u", false, "unpack the raw data too (slower, completes the color data)")
+	exif := flag.Bool("exif", false, "collect every EXIF tag LibRaw parses (JSON only)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-v] [-json] [-u] [-exif] FILE|DIR|GLOB...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	files, err := expand(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rawinfo: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := libraw.NewProcessorOptions()
+	opts.CollectExifTags = *exif && *asJSON
+	p := libraw.NewProcessor(opts)
+
+	status := 0
+	results := make([]result, 0, len(files))
+	for _, path := range files {
+		info, err := p.Identify(path, *unpack)
+		r := result{File: path, FileInfo: info}
+		if err != nil {
+			r.Error = err.Error()
+			status = 1
+		}
+		if *asJSON {
+			results = append(results, r)
+			continue
+		}
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		case *verbose:
+			printVerbose(os.Stdout, path, info)
+		default:
+			m := &info.Metadata.IData
+			fmt.Printf("%s is a %s %s image.\n", path, m.Make, m.Model)
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			fmt.Fprintf(os.Stderr, "rawinfo: %v\n", err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+// expand turns the arguments into a list of files: directories are walked
+// for RAW files (see libraw.IsRawFile) and patterns that name no file are
+// expanded as globs, for shells that do not do it.
+func expand(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		st, err := os.Stat(arg)
+		switch {
+		case err == nil && st.IsDir():
+			err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && libraw.IsRawFile(path) {
+					files = append(files, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		case err == nil:
+			files = append(files, arg)
+		default:
+			matches, globErr := filepath.Glob(arg)
+			if globErr != nil || len(matches) == 0 {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+	}
+	return files, nil
+}
+
+// printVerbose prints info in the layout of raw-identify -v, followed by
+// the lens, levels, previews and warnings.
+func printVerbose(w io.Writer, path string, info *libraw.FileInfo) {
+	m := &info.Metadata
+	fmt.Fprintf(w, "\nFilename: %s\n", path)
+	fmt.Fprintf(w, "Timestamp: %s\n", m.CaptureDate.Format("Mon Jan _2 15:04:05 2006"))
+	fmt.Fprintf(w, "Camera: %s %s\n", m.IData.Make, m.IData.Model)
+	if m.Other.Artist != "" {
+		fmt.Fprintf(w, "Owner: %s\n", m.Other.Artist)
+	}
+	if v := m.IData.DngVersion; v != 0 {
+		fmt.Fprintf(w, "DNG Version: %d.%d.%d.%d\n", v>>24, v>>16&0xff, v>>8&0xff, v&0xff)
+	}
+	fmt.Fprintf(w, "ISO speed: %d\n", int(m.Other.ISOSpeed))
+	fmt.Fprintf(w, "Shutter: ")
+	if s := m.Other.Shutter; s > 0 && s < 1 {
+		fmt.Fprintf(w, "1/%0.1f sec\n", 1/s)
+	} else {
+		fmt.Fprintf(w, "%0.1f sec\n", s)
+	}
+	fmt.Fprintf(w, "Aperture: f/%0.1f\n", m.Other.Aperture)
+	fmt.Fprintf(w, "Focal length: %0.1f mm\n", m.Other.FocalLength)
+	fmt.Fprintf(w, "Embedded ICC profile: %s\n", yesNo(info.ICCProfileLen > 0))
+	fmt.Fprintf(w, "Number of raw images: %d\n", m.IData.RawCount)
+	if info.PixelAspect != 1 && info.PixelAspect != 0 {
+		fmt.Fprintf(w, "Pixel Aspect: %0.6f\n", info.PixelAspect)
+	}
+	if t, ok := largest(info.Thumbnails); ok {
+		fmt.Fprintf(w, "Thumb size:  %4d x %d\n", t.Width, t.Height)
+	}
+	s := &m.Sizes
+	fmt.Fprintf(w, "Full size:   %4d x %d\n", s.RawWidth, s.RawHeight)
+	fmt.Fprintf(w, "Image size:  %4d x %d\n", s.Width, s.Height)
+	ow, oh := s.Iwidth, s.Iheight
+	if s.Flip&4 != 0 {
+		ow, oh = oh, ow
+	}
+	fmt.Fprintf(w, "Output size: %4d x %d\n", ow, oh)
+	fmt.Fprintf(w, "Raw colors: %d\n", m.IData.Colors)
+	if info.FilterPattern != "" {
+		fmt.Fprintf(w, "Filter pattern: %s\n", info.FilterPattern)
+	}
+	c := &m.Color
+	fmt.Fprintf(w, "Daylight multipliers:")
+	for i := range max(min(m.IData.Colors, 4), 0) {
+		fmt.Fprintf(w, " %f", c.PreMul[i])
+	}
+	fmt.Fprintf(w, "\nCamera multipliers:")
+	for _, v := range c.CamMul {
+		fmt.Fprintf(w, " %f", v)
+	}
+	fmt.Fprintln(w)
+
+	l := &m.Lens
+	if l.Model != "" {
+		fmt.Fprintf(w, "Lens: %s\n", l.Model)
+	}
+	if l.MinFocal > 0 {
+		fmt.Fprintf(w, "Lens focal range: %0.1f-%0.1f mm, f/%0.1f-%0.1f\n", l.MinFocal, l.MaxFocal, l.MaxAp4MinFocal, l.MaxAp4MaxFocal)
+	}
+	if sh := &m.ShootingInfo; sh.BodySerial != "" {
+		fmt.Fprintf(w, "Body serial: %s\n", sh.BodySerial)
+	}
+	fmt.Fprintf(w, "Margins: top=%d, left=%d\n", s.TopMargin, s.LeftMargin)
+	fmt.Fprintf(w, "Black level: %d, channels %v\n", c.Black, c.ChannelBlack)
+	fmt.Fprintf(w, "Maximum: %d\n", c.Maximum)
+	if info.Unpacked {
+		fmt.Fprintf(w, "Data maximum: %d\n", c.DataMaximum)
+	}
+	fmt.Fprintf(w, "Raw bits: %d\n", c.RawBps)
+	fmt.Fprintf(w, "Orientation: %d\n", s.Flip)
+
+	fmt.Fprintf(w, "Thumbnails: %d\n", len(info.Thumbnails))
+	for i, t := range info.Thumbnails {
+		fmt.Fprintf(w, "  %d: %s %d x %d, %d bytes at offset %d", i, t.Kind, t.Width, t.Height, t.Length, t.Offset)
+		if t.Kind != libraw.ThumbKindJPEG && t.Bits > 0 {
+			fmt.Fprintf(w, ", %d colors, %d bits", t.Colors, t.Bits)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "Warnings: %s\n", info.Warnings)
+}
+
+func largest(thumbs []libraw.ThumbnailInfo) (libraw.ThumbnailInfo, bool) {
+	var best libraw.ThumbnailInfo
+	for _, t := range thumbs {
+		if t.Width*t.Height > best.Width*best.Height {
+			best = t
+		}
+	}
+	return best, best.Width > 0
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	libraw "github.com/stmtc233/go-libraw"
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+func TestPrintVerbose(t *testing.T) {
+	info := &libraw.FileInfo{
+		Metadata: metadata.ImgMetadata{
+			CaptureDate: time.Date(2024, 3, 5, 14, 7, 9, 0, time.Local),
+			IData:       metadata.LibRawIData{Make: "Canon", Model: "EOS 6D", Colors: 3, RawCount: 1},
+			Sizes: metadata.LibRawSizes{
+				RawWidth: 5568, RawHeight: 3708, Width: 5496, Height: 3670,
+				Iwidth: 5496, Iheight: 3670, Flip: 6,
+			},
+			Other: metadata.ImgOther{ISOSpeed: 400, Shutter: 0.004, Aperture: 5.6, FocalLength: 35},
+		},
+		FilterPattern: "RGGBRGGBRGGBRGGB",
+		Thumbnails:    []libraw.ThumbnailInfo{{Kind: libraw.ThumbKindJPEG, Width: 5472, Height: 3648, Length: 2e6}},
+		Warnings:      libraw.WarnBadCameraWB,
+	}
+
+	var b strings.Builder
+	printVerbose(&b, "IMG_0001.CR2", info)
+	for _, line := range []string{
+		"Filename: IMG_0001.CR2",
+		"Timestamp: Tue Mar  5 14:07:09 2024",
+		"Camera: Canon EOS 6D",
+		"ISO speed: 400",
+		"Shutter: 1/250.0 sec",
+		"Aperture: f/5.6",
+		"Thumb size:  5472 x 3648",
+		"Full size:   5568 x 3708",
+		"Output size: 3670 x 5496",
+		"Filter pattern: RGGBRGGBRGGBRGGB",
+		"  0: jpeg 5472 x 3648, 2000000 bytes at offset 0",
+		"Warnings: bad-camera-wb",
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("missing %q in\n%s", line, b.String())
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.CR2", "b.nef", "notes.txt", "sub/c.dng"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := expand([]string{dir, filepath.Join(dir, "*.txt")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "a.CR2"), filepath.Join(dir, "b.nef"), filepath.Join(dir, "sub/c.dng"),
+		filepath.Join(dir, "notes.txt"),
+	}
+	if !slices.Equal(files, want) {
+		t.Errorf("expand = %v, want %v", files, want)
+	}
+
+	if _, err := expand([]string{filepath.Join(dir, "missing.CR2")}); err == nil {
+		t.Error("missing file accepted")
+	}
+}
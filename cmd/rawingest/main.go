@@ -0,0 +1,87 @@
+// Command rawingest watches directories and converts every RAW file that
+// arrives in them, see package ingest.
+//
+//	rawingest -o /srv/converted -preset camera-look -thumbs /srv/tether
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	libraw "github.com/stmtc233/go-libraw"
+	"github.com/stmtc233/go-libraw/pkg/ingest"
+)
+
+func main() {
+	output := flag.String("o", "", "output directory (required)")
+	preset := flag.String("preset", "", "processing preset, one of "+strings.Join(libraw.Presets(), ", "))
+	profile := flag.String("profile", "", "JSON, YAML or TOML file with the processing options (overrides -preset)")
+	format := flag.String("format", "tiff", "output format: tiff, jpeg or png")
+	quality := flag.Int("q", 90, "JPEG quality")
+	thumbs := flag.Bool("thumbs", false, "also write <name>.thumb.jpg previews")
+	stateFile := flag.String("state", "", "state file (default <output>/.ingest-state.json)")
+	settle := flag.Duration("settle", 0, "how long a file must stay unchanged before it is converted (default 2s)")
+	pollInterval := flag.Duration("poll", 0, "interval of the file checks (default 1s)")
+	forcePoll := flag.Bool("force-poll", false, "poll the directories instead of using file system notifications")
+	jobs := flag.Int("j", 1, "files converted in parallel")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: rawingest -o dir [flags] dir...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 || *output == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := ingest.Config{
+		Dirs:         flag.Args(),
+		Output:       *output,
+		Format:       *format,
+		JPEGQuality:  *quality,
+		Thumbnails:   *thumbs,
+		StateFile:    *stateFile,
+		SettleTime:   *settle,
+		PollInterval: *pollInterval,
+		Poll:         *forcePoll,
+		Workers:      *jobs,
+		OnResult: func(r ingest.Result) {
+			if r.Err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", r.Path, r.Err)
+				return
+			}
+			log.Printf("%s -> %s", r.Path, strings.Join(r.Outputs, ", "))
+		},
+	}
+	switch {
+	case *profile != "":
+		opts, err := libraw.LoadOptions(*profile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.Options = &opts
+	case *preset != "":
+		opts, ok := libraw.LookupPreset(*preset)
+		if !ok {
+			log.Fatalf("unknown preset %q, have %s", *preset, strings.Join(libraw.Presets(), ", "))
+		}
+		cfg.Options = &opts
+	}
+	in, err := ingest.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("watching %s", strings.Join(cfg.Dirs, ", "))
+	if err := in.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
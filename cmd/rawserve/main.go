@@ -0,0 +1,70 @@
+// Command rawserve serves previews, renders and metadata of the RAW files
+// below a directory, see package rawserver.
+//
+//	rawserve -root /photos -addr localhost:8080
+//	curl 'localhost:8080/render?path=2024/IMG_0001.CR2&preset=camera-look&w=1600'
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	libraw "github.com/stmtc233/go-libraw"
+	"github.com/stmtc233/go-libraw/pkg/rawserver"
+)
+
+func main() {
+	root := flag.String("root", ".", "directory to serve")
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	jobs := flag.Int("j", 0, "LibRaw calls in flight (default: number of CPUs)")
+	thumbSize := flag.Int("thumb", 256, "default /thumb size")
+	quality := flag.Int("q", 90, "JPEG quality")
+	profile := flag.String("profile", "", "JSON, YAML or TOML file with the default processing options")
+	flag.Parse()
+
+	cfg := rawserver.Config{Root: *root, MaxConcurrent: *jobs, ThumbSize: *thumbSize, JPEGQuality: *quality}
+	if *profile != "" {
+		opts, err := libraw.LoadOptions(*profile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.Options = &opts
+	}
+	h, err := rawserver.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// Requests get their own contexts, so a signal lets running renders
+	// finish during the shutdown instead of cancelling them.
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	shutdownErr := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		stop() // a second signal kills the process
+		log.Print("shutting down")
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(shutdown)
+	}()
+
+	log.Printf("serving %s on http://%s", *root, *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	if err := <-shutdownErr; err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,223 @@
+// Command rawthumb writes an upright preview image for every RAW file in
+// a set of files and directories, mirroring the directory tree. It uses
+// the largest embedded preview and renders the raw data at half size when
+// a file has none that is large enough.
+//
+//	rawthumb -o previews -size 1024 shoot/     previews/<path below shoot>.jpg
+//	rawthumb -o previews -format png -j 2 a.CR2 b.NEF
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	libraw "github.com/stmtc233/go-libraw"
+	"github.com/stmtc233/go-libraw/pkg/export"
+)
+
+// job converts one RAW file.
+type job struct {
+	src, dst string
+}
+
+type config struct {
+	out     string
+	size    int
+	minSize int
+	format  string
+	quality int
+	workers int
+	verbose bool
+}
+
+func main() {
+	var c config
+	flag.StringVar(&c.out, "o", "thumbs", "output directory")
+	flag.IntVar(&c.size, "size", 0, "fit previews within this many pixels on the longer side (0 = keep size)")
+	flag.IntVar(&c.minSize, "min", -1, "smallest embedded preview to use before rendering the raw data (default -size)")
+	flag.StringVar(&c.format, "format", "jpeg", "output format, jpeg or png")
+	flag.IntVar(&c.quality, "q", 85, "JPEG quality")
+	flag.IntVar(&c.workers, "j", runtime.NumCPU(), "number of files converted in parallel")
+	flag.BoolVar(&c.verbose, "v", false, "report every file written")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] FILE|DIR...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if c.format == "jpg" {
+		c.format = "jpeg"
+	}
+	if c.format != "jpeg" && c.format != "png" {
+		fmt.Fprintf(os.Stderr, "rawthumb: unknown format %q\n", c.format)
+		os.Exit(1)
+	}
+	if c.minSize < 0 {
+		c.minSize = c.size
+	}
+
+	jobs, err := c.plan(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rawthumb: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := c.run(jobs)
+	fmt.Fprintf(os.Stderr, "%d previews written, %d failed\n", len(jobs)-failed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// plan lists the files to convert. Files below a directory argument keep
+// their path relative to it in the output directory; file arguments are
+// written to its top level. Two files that would get the same output, such
+// as IMG_0001.CR2 and IMG_0001.DNG, are an error.
+func (c *config) plan(args []string) ([]job, error) {
+	var jobs []job
+	sources := map[string]string{} // output -> source
+	add := func(src, rel string) error {
+		rel = strings.TrimSuffix(rel, filepath.Ext(rel)) + "." + strings.Replace(c.format, "jpeg", "jpg", 1)
+		dst := filepath.Join(c.out, rel)
+		if prev, ok := sources[dst]; ok {
+			if sameFile(prev, src) {
+				return nil
+			}
+			return fmt.Errorf("%s and %s would both be written to %s", prev, src, dst)
+		}
+		sources[dst] = src
+		jobs = append(jobs, job{src: src, dst: dst})
+		return nil
+	}
+	for _, arg := range args {
+		st, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			if err := add(arg, filepath.Base(arg)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !libraw.IsRawFile(path) {
+				return nil
+			}
+			rel, err := filepath.Rel(arg, path)
+			if err != nil {
+				return err
+			}
+			return add(path, rel)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
+
+// sameFile reports whether two arguments name the same file.
+func sameFile(a, b string) bool {
+	sa, errA := os.Stat(a)
+	sb, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(sa, sb)
+}
+
+// run converts the files with c.workers goroutines, reports failures as
+// they happen and returns their number.
+func (c *config) run(jobs []job) int {
+	p := libraw.NewProcessor(libraw.NewProcessorOptions())
+	queue := make(chan job)
+	var mu sync.Mutex
+	failed := 0
+
+	var wg sync.WaitGroup
+	for range max(c.workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				source, err := c.convert(p, j)
+				mu.Lock()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", j.src, err)
+					failed++
+				} else if c.verbose {
+					fmt.Fprintf(os.Stderr, "%s -> %s (%s)\n", j.src, j.dst, source)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, j := range jobs {
+		queue <- j
+	}
+	close(queue)
+	wg.Wait()
+	return failed
+}
+
+// convert writes the preview of one file and describes where it came from.
+func (c *config) convert(p *libraw.Processor, j job) (string, error) {
+	preview, err := p.Preview(j.src, c.minSize)
+	if err != nil {
+		return "", err
+	}
+	source := "rendered"
+	if t := preview.Thumbnail; t != nil {
+		source = fmt.Sprintf("embedded %s %dx%d", t.Kind, t.Width, t.Height)
+	}
+
+	img := export.Fit(preview.Image, c.size)
+	if err := os.MkdirAll(filepath.Dir(j.dst), 0o755); err != nil {
+		return "", err
+	}
+	return source, writeFile(j.dst, func(w io.Writer) error {
+		if c.format == "png" {
+			return export.EncodePNG(w, img, nil)
+		}
+		return export.EncodeJPEG(w, img, &preview.Metadata, c.quality)
+	})
+}
+
+// writeFile encodes to a temporary file next to name and renames it, so
+// name is never left half written.
+func writeFile(name string, encode func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = encode(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Chmod(0o644) // CreateTemp makes it private
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"day1/a.CR2", "day1/notes.txt", "day2/sub/b.nef", "single.ARW"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &config{out: "out", format: "jpeg"}
+	jobs, err := c.plan([]string{filepath.Join(dir, "day1"), filepath.Join(dir, "day2"), filepath.Join(dir, "single.ARW")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []job{
+		{filepath.Join(dir, "day1/a.CR2"), filepath.Join("out", "a.jpg")},
+		{filepath.Join(dir, "day2/sub/b.nef"), filepath.Join("out", "sub/b.jpg")},
+		{filepath.Join(dir, "single.ARW"), filepath.Join("out", "single.jpg")},
+	}
+	if !slices.Equal(jobs, want) {
+		t.Errorf("plan = %v, want %v", jobs, want)
+	}
+
+	if _, err := c.plan([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("missing input accepted")
+	}
+
+	// the same file twice is converted once
+	single := filepath.Join(dir, "single.ARW")
+	if jobs, err := c.plan([]string{single, single}); err != nil || len(jobs) != 1 {
+		t.Errorf("repeated file: %v, %v", jobs, err)
+	}
+}
+
+func TestPlanCollisions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a/IMG_0001.CR2", "a/IMG_0001.DNG", "b/x/IMG_0002.NEF", "c/x/IMG_0002.NEF"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, nil, 0o644)
+	}
+	c := &config{out: "out", format: "jpeg"}
+	for _, args := range [][]string{
+		{filepath.Join(dir, "a")},
+		{filepath.Join(dir, "b"), filepath.Join(dir, "c")},
+	} {
+		if _, err := c.plan(args); err == nil {
+			t.Errorf("plan(%v) accepted colliding outputs", args)
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.jpg")
+	if err := os.WriteFile(name, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := writeFile(name, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return errors.New("encoding failed")
+	})
+	if err == nil {
+		t.Fatal("error not reported")
+	}
+	if data, _ := os.ReadFile(name); string(data) != "old" {
+		t.Errorf("failed write left %q", data)
+	}
+	if err := writeFile(name, func(w io.Writer) error { _, err := w.Write([]byte("new")); return err }); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(name); string(data) != "new" {
+		t.Errorf("file holds %q", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(name)); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
@@ -0,0 +1,473 @@
+package golibraw
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stmtc233/go-libraw/pkg/icc"
+	"gopkg.in/yaml.v3"
+)
+
+// ProcessorOptions are stored in config files with snake_case keys and
+// names for enums and curves, e.g. in YAML:
+//
+//	preset: camera-look     # optional, start from a registered preset
+//	interpolation: dcb
+//	highlight: blend
+//	output_color: adobe
+//	gamma: srgb             # or "2.4 12.92": gamma and toe slope, as dcraw -g
+//	output_bps: 16
+//	crop: {x: 100, y: 80, w: 4000, h: 3000}
+//	exposure: {shift: 2, preserve: 0.5}
+//	unpack: {shot_select: 1}
+//
+// Keys that are not set keep their value from NewProcessorOptions (or the
+// preset), unknown keys are an error.
+type optionsConfig struct {
+	Preset string `json:"preset,omitempty" yaml:"preset,omitempty" toml:"preset,omitempty"`
+
+	HalfSize        bool               `json:"half_size" yaml:"half_size" toml:"half_size"`
+	Interpolation   Interpolation      `json:"interpolation" yaml:"interpolation" toml:"interpolation"`
+	NoInterpolation bool               `json:"no_interpolation" yaml:"no_interpolation" toml:"no_interpolation"`
+	FourColorRGB    bool               `json:"four_color_rgb" yaml:"four_color_rgb" toml:"four_color_rgb"`
+	DcbIterations   int                `json:"dcb_iterations" yaml:"dcb_iterations" toml:"dcb_iterations"`
+	DcbEnhance      bool               `json:"dcb_enhance" yaml:"dcb_enhance" toml:"dcb_enhance"`
+	FbddNoiserd     FBDDNoiseReduction `json:"fbdd_noise_reduction" yaml:"fbdd_noise_reduction" toml:"fbdd_noise_reduction"`
+	MedianPasses    int                `json:"median_passes" yaml:"median_passes" toml:"median_passes"`
+	DenoiseThr      float32            `json:"denoise_threshold" yaml:"denoise_threshold" toml:"denoise_threshold"`
+	GreenMatching   bool               `json:"green_matching" yaml:"green_matching" toml:"green_matching"`
+	Aberration      *aberrationConfig  `json:"chromatic_aberration,omitempty" yaml:"chromatic_aberration,omitempty" toml:"chromatic_aberration,omitempty"`
+
+	CameraWB      bool              `json:"camera_wb" yaml:"camera_wb" toml:"camera_wb"`
+	AutoWB        bool              `json:"auto_wb" yaml:"auto_wb" toml:"auto_wb"`
+	WBMultipliers *[4]float32       `json:"wb_multipliers,omitempty" yaml:"wb_multipliers,omitempty" toml:"wb_multipliers,omitempty"`
+	WBArea        *rectConfig       `json:"wb_area,omitempty" yaml:"wb_area,omitempty" toml:"wb_area,omitempty"`
+	CameraMatrix  CameraMatrixUsage `json:"camera_matrix" yaml:"camera_matrix" toml:"camera_matrix"`
+
+	Highlight     HighlightMode   `json:"highlight" yaml:"highlight" toml:"highlight"`
+	Brightness    float32         `json:"brightness" yaml:"brightness" toml:"brightness"`
+	NoAutoBright  bool            `json:"no_auto_bright" yaml:"no_auto_bright" toml:"no_auto_bright"`
+	AutoBrightThr float32         `json:"auto_bright_threshold" yaml:"auto_bright_threshold" toml:"auto_bright_threshold"`
+	AdjustMaxThr  float32         `json:"adjust_maximum_threshold" yaml:"adjust_maximum_threshold" toml:"adjust_maximum_threshold"`
+	Exposure      *exposureConfig `json:"exposure,omitempty" yaml:"exposure,omitempty" toml:"exposure,omitempty"`
+	Black         int             `json:"black" yaml:"black" toml:"black"`
+	ChannelBlack  [4]int          `json:"channel_black" yaml:"channel_black" toml:"channel_black"`
+	Saturation    int             `json:"saturation" yaml:"saturation" toml:"saturation"`
+	NoAutoScale   bool            `json:"no_auto_scale" yaml:"no_auto_scale" toml:"no_auto_scale"`
+
+	OutputColor OutputColor `json:"output_color" yaml:"output_color" toml:"output_color"`
+	Gamma       gammaConfig `json:"gamma" yaml:"gamma" toml:"gamma"`
+	OutputBps   int         `json:"output_bps" yaml:"output_bps" toml:"output_bps"`
+	OutputTiff  bool        `json:"output_tiff" yaml:"output_tiff" toml:"output_tiff"`
+	OutputFlags int         `json:"output_flags" yaml:"output_flags" toml:"output_flags"`
+	Flip        Flip        `json:"flip" yaml:"flip" toml:"flip"`
+	FujiRotate  bool        `json:"fuji_rotate" yaml:"fuji_rotate" toml:"fuji_rotate"`
+	Crop        *rectConfig `json:"crop,omitempty" yaml:"crop,omitempty" toml:"crop,omitempty"`
+
+	OutputProfile string `json:"output_profile,omitempty" yaml:"output_profile,omitempty" toml:"output_profile,omitempty"`
+	CameraProfile string `json:"camera_profile,omitempty" yaml:"camera_profile,omitempty" toml:"camera_profile,omitempty"`
+	BadPixels     string `json:"bad_pixels,omitempty" yaml:"bad_pixels,omitempty" toml:"bad_pixels,omitempty"`
+	DarkFrame     string `json:"dark_frame,omitempty" yaml:"dark_frame,omitempty" toml:"dark_frame,omitempty"`
+
+	CollectExifTags bool `json:"collect_exif_tags" yaml:"collect_exif_tags" toml:"collect_exif_tags"`
+
+	Unpack *unpackConfig `json:"unpack,omitempty" yaml:"unpack,omitempty" toml:"unpack,omitempty"`
+}
+
+// unpackConfig is UnpackOptions, omitted when it is the zero value.
+type unpackConfig struct {
+	ShotSelect               uint        `json:"shot_select" yaml:"shot_select" toml:"shot_select"`
+	UseRawSpeed              bool        `json:"use_rawspeed" yaml:"use_rawspeed" toml:"use_rawspeed"`
+	UseDNGSDK                DNGSDKFlags `json:"use_dngsdk" yaml:"use_dngsdk" toml:"use_dngsdk"`
+	Options                  RawOptions  `json:"options" yaml:"options" toml:"options"`
+	MaxRawMemoryMB           uint        `json:"max_raw_memory_mb" yaml:"max_raw_memory_mb" toml:"max_raw_memory_mb"`
+	SonyARW2PosterizationThr int         `json:"sony_arw2_posterization_thr" yaml:"sony_arw2_posterization_thr" toml:"sony_arw2_posterization_thr"`
+	CoolscanNEFGamma         float32     `json:"coolscan_nef_gamma" yaml:"coolscan_nef_gamma" toml:"coolscan_nef_gamma"`
+	P4ShotOrder              string      `json:"p4shot_order" yaml:"p4shot_order" toml:"p4shot_order"`
+	CustomCameras            []string    `json:"custom_cameras,omitempty" yaml:"custom_cameras,omitempty" toml:"custom_cameras,omitempty"`
+}
+
+// rectConfig is a Box in its x, y, width, height form.
+type rectConfig struct {
+	X uint `json:"x" yaml:"x" toml:"x"`
+	Y uint `json:"y" yaml:"y" toml:"y"`
+	W uint `json:"w" yaml:"w" toml:"w"`
+	H uint `json:"h" yaml:"h" toml:"h"`
+}
+
+// aberrationConfig holds the red and blue scale factors of Aber.
+type aberrationConfig struct {
+	Red  float64 `json:"red" yaml:"red" toml:"red"`
+	Blue float64 `json:"blue" yaml:"blue" toml:"blue"`
+}
+
+// exposureConfig is the exposure correction, present when ExpCorrect is set.
+type exposureConfig struct {
+	Shift    float32 `json:"shift" yaml:"shift" toml:"shift"`
+	Preserve float32 `json:"preserve" yaml:"preserve" toml:"preserve"`
+}
+
+// gammaConfig is Gamm[0] and Gamm[1]. It is written as the name of a
+// standard curve or as "<gamma> <toe slope>" like dcraw -g, so the sRGB
+// curve is "srgb" or "2.4 12.92".
+type gammaConfig [2]float64
+
+var curveNames = []struct {
+	name  string
+	curve icc.ToneCurve
+}{
+	{"bt709", icc.BT709},
+	{"srgb", icc.SRGB},
+	{"linear", icc.Linear},
+	{"gamma1.8", icc.Gamma18},
+	{"gamma2.2", icc.Gamma22},
+	{"gamma2.6", icc.Gamma26},
+}
+
+func (g gammaConfig) MarshalText() ([]byte, error) {
+	for _, c := range curveNames {
+		if g[0] == c.curve.Power && g[1] == c.curve.Slope {
+			return []byte(c.name), nil
+		}
+	}
+	if g[0] <= 0 {
+		return nil, fmt.Errorf("gamma power %v cannot be written", g[0])
+	}
+	return []byte(strconv.FormatFloat(1/g[0], 'g', -1, 64) + " " + strconv.FormatFloat(g[1], 'g', -1, 64)), nil
+}
+
+func (g *gammaConfig) UnmarshalText(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	for _, c := range curveNames {
+		if strings.EqualFold(c.name, s) {
+			*g = gammaConfig{c.curve.Power, c.curve.Slope}
+			return nil
+		}
+	}
+	f := strings.Fields(s)
+	if len(f) == 2 {
+		gamma, err1 := strconv.ParseFloat(f[0], 64)
+		slope, err2 := strconv.ParseFloat(f[1], 64)
+		if err1 == nil && err2 == nil && gamma > 0 {
+			*g = gammaConfig{1 / gamma, slope}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown gamma %q, want bt709, srgb, linear, gamma1.8, gamma2.2, gamma2.6 or \"<gamma> <slope>\"", s)
+}
+
+func newRectConfig(b Box) *rectConfig {
+	if b.IsEmpty() {
+		return nil
+	}
+	return &rectConfig{b.X1, b.Y1, b.X2, b.Y2}
+}
+
+func (r *rectConfig) box() Box {
+	if r == nil {
+		return Box{}
+	}
+	return Box{r.X, r.Y, r.W, r.H}
+}
+
+func newOptionsConfig(opts *ProcessorOptions) optionsConfig {
+	c := optionsConfig{
+		HalfSize:        opts.HalfSize,
+		Interpolation:   opts.UserQual,
+		NoInterpolation: opts.NoInterpolation,
+		FourColorRGB:    opts.FourColorRGB,
+		DcbIterations:   opts.DcbIterations,
+		DcbEnhance:      opts.DcbEnhanceFl,
+		FbddNoiserd:     opts.FbddNoiserd,
+		MedianPasses:    opts.MedPasses,
+		DenoiseThr:      opts.Threshold,
+		GreenMatching:   opts.GreenMatching,
+
+		CameraWB:     opts.UseCameraWb,
+		AutoWB:       opts.UseAutoWb,
+		WBArea:       newRectConfig(opts.Greybox),
+		CameraMatrix: opts.UseCameraMatrix,
+
+		Highlight:     opts.Highlight,
+		Brightness:    opts.Bright,
+		NoAutoBright:  opts.NoAutoBright,
+		AutoBrightThr: opts.AutoBrightThr,
+		AdjustMaxThr:  opts.AdjustMaximumThr,
+		Black:         opts.UserBlack,
+		ChannelBlack:  opts.UserCblack,
+		Saturation:    opts.UserSat,
+		NoAutoScale:   opts.NoAutoScale,
+
+		OutputColor: opts.OutputColor,
+		Gamma:       gammaConfig{opts.Gamm[0], opts.Gamm[1]},
+		OutputBps:   opts.OutputBps,
+		OutputTiff:  opts.OutputTiff,
+		OutputFlags: opts.OutputFlags,
+		Flip:        opts.UserFlip,
+		FujiRotate:  opts.UseFujiRotate,
+		Crop:        newRectConfig(opts.Cropbox),
+
+		OutputProfile: opts.OutputProfile,
+		CameraProfile: opts.CameraProfile,
+		BadPixels:     opts.BadPixels,
+		DarkFrame:     opts.DarkFrame,
+
+		CollectExifTags: opts.CollectExifTags,
+	}
+	if opts.Aber[0] != 1 || opts.Aber[2] != 1 {
+		c.Aberration = &aberrationConfig{opts.Aber[0], opts.Aber[2]}
+	}
+	if opts.UserMul != [4]float32{} {
+		mul := opts.UserMul
+		c.WBMultipliers = &mul
+	}
+	if opts.ExpCorrect {
+		c.Exposure = &exposureConfig{opts.ExpShift, opts.ExpPreser}
+	}
+	if u := &opts.Unpack; !u.IsZero() {
+		c.Unpack = &unpackConfig{
+			ShotSelect:               u.ShotSelect,
+			UseRawSpeed:              u.UseRawSpeed,
+			UseDNGSDK:                u.UseDNGSDK,
+			Options:                  u.Options,
+			MaxRawMemoryMB:           u.MaxRawMemoryMB,
+			SonyARW2PosterizationThr: u.SonyARW2PosterizationThr,
+			CoolscanNEFGamma:         u.CoolscanNEFGamma,
+			P4ShotOrder:              u.P4ShotOrder,
+			CustomCameras:            slices.Clone(u.CustomCameras),
+		}
+	}
+	return c
+}
+
+func (c *optionsConfig) options() ProcessorOptions {
+	opts := NewProcessorOptions()
+
+	opts.HalfSize = c.HalfSize
+	opts.UserQual = c.Interpolation
+	opts.NoInterpolation = c.NoInterpolation
+	opts.FourColorRGB = c.FourColorRGB
+	opts.DcbIterations = c.DcbIterations
+	opts.DcbEnhanceFl = c.DcbEnhance
+	opts.FbddNoiserd = c.FbddNoiserd
+	opts.MedPasses = c.MedianPasses
+	opts.Threshold = c.DenoiseThr
+	opts.GreenMatching = c.GreenMatching
+	if c.Aberration != nil {
+		opts.Aber[0], opts.Aber[2] = c.Aberration.Red, c.Aberration.Blue
+	}
+
+	opts.UseCameraWb = c.CameraWB
+	opts.UseAutoWb = c.AutoWB
+	if c.WBMultipliers != nil {
+		opts.UserMul = *c.WBMultipliers
+	}
+	opts.Greybox = c.WBArea.box()
+	opts.UseCameraMatrix = c.CameraMatrix
+
+	opts.Highlight = c.Highlight
+	opts.Bright = c.Brightness
+	opts.NoAutoBright = c.NoAutoBright
+	opts.AutoBrightThr = c.AutoBrightThr
+	opts.AdjustMaximumThr = c.AdjustMaxThr
+	if c.Exposure != nil {
+		opts.ExpCorrect = true
+		opts.ExpShift, opts.ExpPreser = c.Exposure.Shift, c.Exposure.Preserve
+	}
+	opts.UserBlack = c.Black
+	opts.UserCblack = c.ChannelBlack
+	opts.UserSat = c.Saturation
+	opts.NoAutoScale = c.NoAutoScale
+
+	opts.OutputColor = c.OutputColor
+	opts.Gamm = [6]float64{c.Gamma[0], c.Gamma[1]}
+	opts.OutputBps = c.OutputBps
+	opts.OutputTiff = c.OutputTiff
+	opts.OutputFlags = c.OutputFlags
+	opts.UserFlip = c.Flip
+	opts.UseFujiRotate = c.FujiRotate
+	opts.Cropbox = c.Crop.box()
+
+	opts.OutputProfile = c.OutputProfile
+	opts.CameraProfile = c.CameraProfile
+	opts.BadPixels = c.BadPixels
+	opts.DarkFrame = c.DarkFrame
+
+	opts.CollectExifTags = c.CollectExifTags
+
+	opts.Unpack = UnpackOptions{}
+	if u := c.Unpack; u != nil {
+		opts.Unpack = UnpackOptions{
+			ShotSelect:               u.ShotSelect,
+			UseRawSpeed:              u.UseRawSpeed,
+			UseDNGSDK:                u.UseDNGSDK,
+			Options:                  u.Options,
+			MaxRawMemoryMB:           u.MaxRawMemoryMB,
+			SonyARW2PosterizationThr: u.SonyARW2PosterizationThr,
+			CoolscanNEFGamma:         u.CoolscanNEFGamma,
+			P4ShotOrder:              u.P4ShotOrder,
+			CustomCameras:            u.CustomCameras,
+		}
+	}
+	return opts
+}
+
+// Config formats understood by MarshalOptions and UnmarshalOptions.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// FormatForPath returns the config format of a file by its extension
+// (.json, .yaml, .yml or .toml).
+func FormatForPath(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".toml":
+		return FormatTOML, nil
+	default:
+		return "", fmt.Errorf("unknown config format %q", ext)
+	}
+}
+
+// MarshalOptions encodes opts in format. Every setting is written, so the
+// file documents the complete render.
+func MarshalOptions(opts ProcessorOptions, format string) ([]byte, error) {
+	c := newOptionsConfig(&opts)
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(c, "", "  ")
+	case FormatYAML:
+		return yaml.Marshal(c)
+	case FormatTOML:
+		var buf bytes.Buffer
+		err := toml.NewEncoder(&buf).Encode(c)
+		return buf.Bytes(), err
+	}
+	return nil, fmt.Errorf("unknown config format %q", format)
+}
+
+// UnmarshalOptions decodes options in format. Settings start out as the
+// preset named by the "preset" key, or NewProcessorOptions if there is
+// none, and are overridden by the other keys. Unknown keys are an error.
+func UnmarshalOptions(data []byte, format string) (ProcessorOptions, error) {
+	var base struct {
+		Preset string `json:"preset" yaml:"preset" toml:"preset"`
+	}
+	if err := decodeConfig(data, format, &base, false); err != nil {
+		return ProcessorOptions{}, err
+	}
+	opts := NewProcessorOptions()
+	if base.Preset != "" {
+		var ok bool
+		if opts, ok = LookupPreset(base.Preset); !ok {
+			return ProcessorOptions{}, fmt.Errorf("unknown preset %q", base.Preset)
+		}
+	}
+
+	c := newOptionsConfig(&opts)
+	if err := decodeConfig(data, format, &c, true); err != nil {
+		return ProcessorOptions{}, err
+	}
+	return c.options(), nil
+}
+
+func decodeConfig(data []byte, format string, v any, strict bool) error {
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		if strict {
+			dec.DisallowUnknownFields()
+		}
+		return dec.Decode(v)
+	case FormatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(strict)
+		if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	case FormatTOML:
+		md, err := toml.Decode(string(data), v)
+		if err != nil {
+			return err
+		}
+		if keys := md.Undecoded(); strict && len(keys) > 0 {
+			return fmt.Errorf("toml: unknown field %q", keys[0].String())
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown config format %q", format)
+}
+
+// LoadOptions reads options from a JSON, YAML or TOML file, see
+// UnmarshalOptions.
+func LoadOptions(path string) (ProcessorOptions, error) {
+	format, err := FormatForPath(path)
+	if err != nil {
+		return ProcessorOptions{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ProcessorOptions{}, err
+	}
+	opts, err := UnmarshalOptions(data, format)
+	if err != nil {
+		return ProcessorOptions{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return opts, nil
+}
+
+// SaveOptions writes options to a file in the format of its extension.
+func SaveOptions(path string, opts ProcessorOptions) error {
+	format, err := FormatForPath(path)
+	if err != nil {
+		return err
+	}
+	data, err := MarshalOptions(opts, format)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// MarshalJSON encodes the options like MarshalOptions, so ProcessorOptions
+// can be embedded in larger JSON configs.
+func (opts ProcessorOptions) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newOptionsConfig(&opts))
+}
+
+// UnmarshalJSON decodes the options like UnmarshalOptions.
+func (opts *ProcessorOptions) UnmarshalJSON(data []byte) (err error) {
+	*opts, err = UnmarshalOptions(data, FormatJSON)
+	return err
+}
+
+// MarshalYAML encodes the options like MarshalOptions, so ProcessorOptions
+// can be embedded in larger YAML configs.
+func (opts ProcessorOptions) MarshalYAML() (any, error) {
+	return newOptionsConfig(&opts), nil
+}
+
+// UnmarshalYAML decodes the options like UnmarshalOptions.
+func (opts *ProcessorOptions) UnmarshalYAML(node *yaml.Node) error {
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+	*opts, err = UnmarshalOptions(data, FormatYAML)
+	return err
+}
@@ -0,0 +1,117 @@
+package golibraw
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestOptionsConfigRoundTrip(t *testing.T) {
+	opts := CameraLookOptions()
+	opts.Cropbox = Box{100, 80, 4000, 3000}
+	opts.Gamm = [6]float64{1 / 2.2, 3}
+	opts.UserMul = [4]float32{2, 1, 1.5, 1}
+	opts.ExpCorrect, opts.ExpShift, opts.ExpPreser = true, 2, 0.5
+	opts.Aber[0], opts.Aber[2] = 1.001, 0.999
+	opts.Highlight = 6
+	opts.Unpack = UnpackOptions{
+		ShotSelect: 1, UseDNGSDK: DNGSDKDefault, Options: RawConvertFloatToInt | RawDNGAddEnhanced,
+		MaxRawMemoryMB: 2048, CoolscanNEFGamma: 1, P4ShotOrder: "3102",
+		CustomCameras: []string{"1868480,1152,1080,0,0,0,0,0,0x94,0,0,Ricoh,GR Digital"},
+	}
+
+	for _, format := range []string{FormatJSON, FormatYAML, FormatTOML} {
+		path := filepath.Join(t.TempDir(), "render."+format)
+		if err := SaveOptions(path, opts); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		got, err := LoadOptions(path)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !reflect.DeepEqual(got, opts) {
+			t.Errorf("%s round trip:\n got %+v\nwant %+v", format, got, opts)
+		}
+	}
+}
+
+func TestUnmarshalOptions(t *testing.T) {
+	data := []byte(`
+preset: fast-preview
+interpolation: ahd
+gamma: srgb
+crop: {x: 10, y: 20, w: 300, h: 200}
+`)
+	opts, err := UnmarshalOptions(data, FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FastPreviewOptions()
+	want.UserQual = InterpolationAHD
+	want.UseStandardCurve()
+	want.Cropbox = Box{10, 20, 300, 200}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("got %+v\nwant %+v", opts, want)
+	}
+
+	opts, err = UnmarshalOptions([]byte(`output_bps = 16`), FormatTOML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = NewProcessorOptions()
+	want.OutputBps = 16
+	if !reflect.DeepEqual(opts, want) {
+		t.Error("missing keys do not default to NewProcessorOptions")
+	}
+
+	for format, data := range map[string]string{
+		FormatJSON: `{"interpolaton": "ahd"}`,
+		FormatYAML: "half_size: true\nhalfsize: true\n",
+		FormatTOML: "[crop]\nx = 1\nwidth = 2\n",
+	} {
+		if _, err := UnmarshalOptions([]byte(data), format); err == nil {
+			t.Errorf("%s: unknown key accepted", format)
+		}
+	}
+	if _, err := UnmarshalOptions([]byte(`{"flip": "sideways"}`), FormatJSON); err == nil {
+		t.Error("unknown flip accepted")
+	}
+	if _, err := UnmarshalOptions([]byte(`{"preset": "nope"}`), FormatJSON); err == nil {
+		t.Error("unknown preset accepted")
+	}
+}
+
+func TestOptionsEmbedded(t *testing.T) {
+	type service struct {
+		Name   string           `json:"name" yaml:"name"`
+		Render ProcessorOptions `json:"render" yaml:"render"`
+	}
+	in := service{"thumbs", FastPreviewOptions()}
+
+	data, err := yaml.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out service
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("YAML round trip: got %+v", out)
+	}
+
+	data, err = json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = service{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("JSON round trip: got %+v", out)
+	}
+}
@@ -0,0 +1,82 @@
+package golibraw
+
+// #include <stdint.h>
+// #include "libraw/libraw.h"
+//
+// void gorawSetProgressHandler(libraw_data_t *lr, uintptr_t handle);
+import "C"
+
+import (
+	"context"
+	"runtime/cgo"
+)
+
+// WithContext returns a copy of p whose processing calls stop when ctx is
+// done. LibRaw checks for cancellation between processing stages, so a
+// call may run on briefly; it then returns ctx.Err().
+func (p *Processor) WithContext(ctx context.Context) *Processor {
+	q := *p
+	q.ctx = ctx
+	return &q
+}
+
+// derive returns a Processor with other options and the context of p.
+func (p *Processor) derive(opts ProcessorOptions) *Processor {
+	q := NewProcessor(opts)
+	q.ctx = p.ctx
+	return q
+}
+
+// cancelWatch cancels LibRaw processing once its context is done.
+type cancelWatch struct {
+	handle cgo.Handle
+	proc   *C.libraw_data_t
+	ctx    context.Context
+}
+
+// watchContext registers a progress handler on proc if p has a context.
+// The returned watch must be stopped before proc is closed; a nil watch
+// is valid and watches nothing.
+func (p *Processor) watchContext(proc *C.libraw_data_t) *cancelWatch {
+	if p.ctx == nil || p.ctx.Done() == nil {
+		return nil
+	}
+	w := &cancelWatch{proc: proc, ctx: p.ctx}
+	w.handle = cgo.NewHandle(w)
+	C.gorawSetProgressHandler(proc, C.uintptr_t(w.handle))
+	return w
+}
+
+// stop unregisters the handler.
+func (w *cancelWatch) stop() {
+	if w == nil {
+		return
+	}
+	C.gorawSetProgressHandler(w.proc, 0)
+	w.handle.Delete()
+}
+
+// err replaces the error of a cancelled LibRaw call with the context's.
+func (w *cancelWatch) err(err error) error {
+	if err != nil && w != nil && w.ctx.Err() != nil {
+		return w.ctx.Err()
+	}
+	return err
+}
+
+// ctxErr reports a context that is already done before any work starts.
+func (p *Processor) ctxErr() error {
+	if p.ctx == nil {
+		return nil
+	}
+	return p.ctx.Err()
+}
+
+//export goLibrawCancelled
+func goLibrawCancelled(handle C.uintptr_t) C.int {
+	w := cgo.Handle(handle).Value().(*cancelWatch)
+	if w.ctx.Err() != nil {
+		return 1
+	}
+	return 0
+}
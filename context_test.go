@@ -0,0 +1,50 @@
+package golibraw
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := NewProcessor(NewProcessorOptions()).WithContext(ctx)
+
+	if _, _, err := p.ProcessRaw("does-not-matter.CR2"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ProcessRaw with a cancelled context: %v", err)
+	}
+	if _, _, err := p.UnpackRaw("does-not-matter.CR2"); !errors.Is(err, context.Canceled) {
+		t.Errorf("UnpackRaw with a cancelled context: %v", err)
+	}
+	if _, err := p.Identify("does-not-matter.CR2", true); !errors.Is(err, context.Canceled) {
+		t.Errorf("Identify with a cancelled context: %v", err)
+	}
+	if _, err := p.ExtractThumbnail("does-not-matter.CR2"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExtractThumbnail with a cancelled context: %v", err)
+	}
+	if _, err := p.ExtractThumbnailIndex("does-not-matter.CR2", 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExtractThumbnailIndex with a cancelled context: %v", err)
+	}
+	if _, err := p.Preview("does-not-matter.CR2", 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Preview with a cancelled context: %v", err)
+	}
+	if p.derive(NewProcessorOptions()).ctx != ctx {
+		t.Error("derived processor lost its context")
+	}
+
+	for _, path := range getAllFilesInTestDir() {
+		ctx, cancel := context.WithCancel(context.Background())
+		p := NewProcessor(NewProcessorOptions()).WithContext(ctx)
+		if _, _, err := p.ProcessRaw(path); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+		cancel()
+		if _, _, err := p.ProcessRaw(path); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: processed after cancel: %v", path, err)
+		}
+		if _, err := p.Identify(path, true); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: identified after cancel: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package golibraw
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"io"
+
+	"github.com/stmtc233/go-libraw/pkg/dng"
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// xyzToSRGB (D65) stands in for the camera matrix of cameras LibRaw has no
+// color data for, matching its own fallback of an identity rgb_cam.
+var xyzToSRGB = [3][3]float64{
+	{3.2404542, -1.5371385, -0.4985314},
+	{-0.9692660, 1.8760108, 0.0415560},
+	{0.0556434, -0.2040259, 1.0572252},
+}
+
+// ExportDNG converts a RAW file to DNG. The CFA data, black and white
+// levels, color matrix, as shot white balance, orientation and EXIF tags
+// are taken from the file, and its embedded thumbnail becomes the DNG
+// preview unless opts.Preview is already set. Only RGB color filter
+// arrays (Bayer and X-Trans) are supported.
+func (p *Processor) ExportDNG(w io.Writer, filepath string, opts *dng.Options) error {
+	raw, meta, err := p.UnpackRaw(filepath)
+	if err != nil {
+		return err
+	}
+
+	img, err := raw.dngImage(&meta)
+	if err != nil {
+		return err
+	}
+
+	dngOpts := dng.Options{}
+	if opts != nil {
+		dngOpts = *opts
+	}
+	if dngOpts.Metadata == nil {
+		dngOpts.Metadata = &meta
+	}
+	if dngOpts.Preview == nil {
+		// a missing thumbnail only means the DNG has no preview
+		if thumb, err := p.ExtractThumbnail(filepath); err == nil {
+			dngOpts.Preview = thumb.dngPreview()
+		}
+	}
+
+	return dng.Encode(w, img, &dngOpts)
+}
+
+// dngPreview returns the thumbnail as a DNG preview, or nil when its
+// format or size cannot be written.
+func (t *Thumbnail) dngPreview() *dng.Preview {
+	w, h := int(t.Width), int(t.Height)
+	switch {
+	case t.Format == ThumbJpeg:
+		// LibRaw does not know the size of every JPEG preview
+		if w == 0 || h == 0 {
+			cfg, err := jpeg.DecodeConfig(bytes.NewReader(t.Data))
+			if err != nil {
+				return nil
+			}
+			w, h = cfg.Width, cfg.Height
+		}
+		if w == 0 || h == 0 {
+			return nil
+		}
+		return &dng.Preview{Width: w, Height: h, Data: t.Data, JPEG: true}
+	case t.Format == ThumbBitmap && t.Colors == 3 && t.Bits == 8 && w > 0 && h > 0 && len(t.Data) == w*h*3:
+		return &dng.Preview{Width: w, Height: h, Data: t.Data}
+	}
+	return nil
+}
+
+// dngImage crops the raw data to its visible area and translates LibRaw's
+// color indices into DNG plane colors.
+func (r *RawImage) dngImage(meta *metadata.ImgMetadata) (*dng.Image, error) {
+	cdesc := meta.IData.ColorDescription
+	planeOf := func(color int) (uint8, error) {
+		if color >= 0 && color < len(cdesc) {
+			switch cdesc[color] {
+			case 'R':
+				return dng.Red, nil
+			case 'G':
+				return dng.Green, nil
+			case 'B':
+				return dng.Blue, nil
+			}
+		}
+		return 0, fmt.Errorf("dng: unsupported color filter array %q", string(cdesc[:]))
+	}
+
+	rows, cols := r.cfaPeriod()
+	if rows == 0 {
+		return nil, fmt.Errorf("dng: file has no supported color filter array")
+	}
+	cfa := make([][]uint8, rows)
+	for y := range rows {
+		cfa[y] = make([]uint8, cols)
+		for x := range cols {
+			plane, err := planeOf(r.CFAColor(y, x))
+			if err != nil {
+				return nil, err
+			}
+			cfa[y][x] = plane
+		}
+	}
+
+	color := &meta.Color
+	blackRows, blackCols := rows, cols
+	if n := len(color.BlackPattern); n > 0 {
+		blackRows, blackCols = lcm(rows, n), lcm(cols, len(color.BlackPattern[0]))
+	}
+	black := make([][]uint32, blackRows)
+	for y := range blackRows {
+		black[y] = make([]uint32, blackCols)
+		for x := range blackCols {
+			black[y][x] = color.BlackAt(y, x, r.CFAColor(y, x))
+		}
+	}
+
+	img := &dng.Image{
+		Width:       r.Visible.Dx(),
+		Height:      r.Visible.Dy(),
+		Pix:         make([]uint16, 0, r.Visible.Dx()*r.Visible.Dy()),
+		CFA:         cfa,
+		BlackLevel:  black,
+		WhiteLevel:  color.Maximum,
+		ColorMatrix: xyzToSRGB,
+		Orientation: meta.Sizes.ExifOrientation(),
+	}
+	for y := r.Visible.Min.Y; y < r.Visible.Max.Y; y++ {
+		img.Pix = append(img.Pix, r.Pix[y*r.Width+r.Visible.Min.X:y*r.Width+r.Visible.Max.X]...)
+	}
+
+	// camera matrix and neutral rows in plane order, using the first
+	// channel of each color (the second green duplicates the first)
+	neutral, hasNeutral := color.AsShotNeutral()
+	var matrix [3][3]float64
+	var hasMatrix bool
+	for plane := range 3 {
+		for c := range len(cdesc) {
+			if p, err := planeOf(c); err != nil || int(p) != plane {
+				continue
+			}
+			for j := range 3 {
+				matrix[plane][j] = float64(color.CamXYZ[c][j])
+				hasMatrix = hasMatrix || matrix[plane][j] != 0
+			}
+			if hasNeutral {
+				img.AsShotNeutral[plane] = neutral[c]
+			}
+			break
+		}
+	}
+	if hasMatrix {
+		img.ColorMatrix = matrix
+	}
+
+	return img, nil
+}
+
+// cfaPeriod returns the size of the repeating CFA tile: 6x6 for X-Trans,
+// and for Bayer style filters the smallest row period (out of the 8 rows
+// the LibRaw bitmask can describe) by 2 columns. It returns 0, 0 when
+// there is no CFA.
+func (r *RawImage) cfaPeriod() (int, int) {
+	switch {
+	case r.Filters == 9:
+		return 6, 6
+	case r.Filters < 1000:
+		return 0, 0
+	}
+	for rows := 1; rows < 8; rows *= 2 {
+		bitsPerPeriod := uint(rows * 4) // 2 columns of 2 bits per row
+		mask := uint32(1)<<bitsPerPeriod - 1
+		period := r.Filters & mask
+		repeated := period
+		for shift := bitsPerPeriod; shift < 32; shift += bitsPerPeriod {
+			repeated |= period << shift
+		}
+		if repeated == r.Filters {
+			return max(rows, 2), 2
+		}
+	}
+	return 8, 2
+}
+
+func lcm(a, b int) int {
+	x, y := a, b
+	for y != 0 {
+		x, y = y, x%y
+	}
+	return a / x * b
+}
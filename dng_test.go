@@ -0,0 +1,80 @@
+package golibraw
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stmtc233/go-libraw/pkg/dng"
+)
+
+// TestDNGRoundTrip converts each test file to DNG and checks that LibRaw
+// reads back the same sensor data, levels and camera.
+func TestDNGRoundTrip(t *testing.T) {
+	processor := NewProcessor(NewProcessorOptions())
+
+	for _, path := range getAllFilesInTestDir() {
+		orig, origMeta, err := processor.UnpackRaw(path)
+		if err != nil {
+			t.Fatalf("UnpackRaw failed: %v", err)
+		}
+
+		for _, c := range []dng.Compression{dng.Uncompressed, dng.LosslessJPEG} {
+			out := filepath.Join(t.TempDir(), "out.dng")
+			f, err := os.Create(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = processor.ExportDNG(f, path, &dng.Options{Compression: c})
+			f.Close()
+			if err != nil {
+				t.Fatalf("ExportDNG failed for '%s': %v", path, err)
+			}
+
+			back, backMeta, err := processor.UnpackRaw(out)
+			if err != nil {
+				t.Fatalf("LibRaw cannot read DNG written for '%s': %v", path, err)
+			}
+
+			if !slices.Equal(back.Image().Pix, orig.Image().Pix) {
+				t.Errorf("DNG of '%s' (compression %d) has different sensor data", path, c)
+			}
+			if backMeta.IData.Make != origMeta.IData.Make || backMeta.IData.Model != origMeta.IData.Model {
+				t.Errorf("DNG camera %s %s, want %s %s", backMeta.IData.Make, backMeta.IData.Model,
+					origMeta.IData.Make, origMeta.IData.Model)
+			}
+			if backMeta.Color.Maximum != origMeta.Color.Maximum {
+				t.Errorf("DNG white level %d, want %d", backMeta.Color.Maximum, origMeta.Color.Maximum)
+			}
+			if backMeta.IData.DngVersion == 0 {
+				t.Errorf("LibRaw did not recognize '%s' as DNG", out)
+			}
+		}
+	}
+}
+
+func TestDNGPreviewSize(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 160, 120)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// LibRaw reports no size for some JPEG previews
+	p := (&Thumbnail{Format: ThumbJpeg, Data: buf.Bytes()}).dngPreview()
+	if p == nil || p.Width != 160 || p.Height != 120 {
+		t.Errorf("JPEG preview without size = %+v", p)
+	}
+	if p := (&Thumbnail{Format: ThumbJpeg, Data: []byte("not a JPEG")}).dngPreview(); p != nil {
+		t.Errorf("undecodable preview of unknown size kept: %+v", p)
+	}
+	if p := (&Thumbnail{Format: ThumbBitmap, Colors: 3, Bits: 8, Data: make([]byte, 12)}).dngPreview(); p != nil {
+		t.Errorf("bitmap preview of unknown size kept: %+v", p)
+	}
+	if p := (&Thumbnail{Format: ThumbBitmap, Width: 2, Height: 2, Colors: 3, Bits: 8, Data: make([]byte, 12)}).dngPreview(); p == nil {
+		t.Error("bitmap preview dropped")
+	}
+}
@@ -0,0 +1,274 @@
+package golibraw
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Interpolation is the demosaicing algorithm, LibRaw's user_qual.
+type Interpolation int
+
+const (
+	InterpolationDefault Interpolation = -1 // AHD
+	InterpolationLinear  Interpolation = 0
+	InterpolationVNG     Interpolation = 1
+	InterpolationPPG     Interpolation = 2
+	InterpolationAHD     Interpolation = 3
+	InterpolationDCB     Interpolation = 4
+	InterpolationDHT     Interpolation = 11
+	InterpolationAAHD    Interpolation = 12
+)
+
+var interpolationNames = []enumName[Interpolation]{
+	{InterpolationDefault, "default"},
+	{InterpolationLinear, "linear"},
+	{InterpolationVNG, "vng"},
+	{InterpolationPPG, "ppg"},
+	{InterpolationAHD, "ahd"},
+	{InterpolationDCB, "dcb"},
+	{InterpolationDHT, "dht"},
+	{InterpolationAAHD, "aahd"},
+}
+
+// HighlightMode selects how clipped highlights are handled, LibRaw's
+// highlight. Values 3 to 9 rebuild highlights, higher values favour
+// white over color.
+type HighlightMode int
+
+const (
+	HighlightClip    HighlightMode = 0
+	HighlightUnclip  HighlightMode = 1
+	HighlightBlend   HighlightMode = 2
+	HighlightRebuild HighlightMode = 3
+)
+
+var highlightNames = []enumName[HighlightMode]{
+	{HighlightClip, "clip"},
+	{HighlightUnclip, "unclip"},
+	{HighlightBlend, "blend"},
+	{HighlightRebuild, "rebuild"},
+	{4, "rebuild4"},
+	{5, "rebuild5"},
+	{6, "rebuild6"},
+	{7, "rebuild7"},
+	{8, "rebuild8"},
+	{9, "rebuild9"},
+}
+
+// FBDDNoiseReduction is the strength of the FBDD noise reduction done
+// before demosaicing, LibRaw's fbdd_noiserd.
+type FBDDNoiseReduction int
+
+const (
+	FBDDOff   FBDDNoiseReduction = 0
+	FBDDLight FBDDNoiseReduction = 1
+	FBDDFull  FBDDNoiseReduction = 2
+)
+
+var fbddNames = []enumName[FBDDNoiseReduction]{
+	{FBDDOff, "off"},
+	{FBDDLight, "light"},
+	{FBDDFull, "full"},
+}
+
+// CameraMatrixUsage controls when the color matrix embedded in the file is
+// used instead of LibRaw's own, LibRaw's use_camera_matrix.
+type CameraMatrixUsage int
+
+const (
+	CameraMatrixNever CameraMatrixUsage = 0
+	// CameraMatrixDefault uses the embedded matrix for DNG files, and for
+	// other files only together with UseCameraWb.
+	CameraMatrixDefault CameraMatrixUsage = 1
+	CameraMatrixAlways  CameraMatrixUsage = 3
+)
+
+var cameraMatrixNames = []enumName[CameraMatrixUsage]{
+	{CameraMatrixNever, "never"},
+	{CameraMatrixDefault, "default"},
+	{CameraMatrixAlways, "always"},
+}
+
+// Flip is an output rotation or mirroring, LibRaw's user_flip. The bits
+// are 1 = mirror horizontally, 2 = mirror vertically, 4 = swap axes.
+type Flip int
+
+const (
+	FlipAsShot     Flip = -1 // use the orientation stored in the file
+	FlipNone       Flip = 0
+	FlipHorizontal Flip = 1
+	FlipVertical   Flip = 2
+	FlipRotate180  Flip = 3
+	FlipTranspose  Flip = 4
+	FlipRotate270  Flip = 5 // 90° counter-clockwise
+	FlipRotate90   Flip = 6 // 90° clockwise
+	FlipTransverse Flip = 7
+)
+
+var flipNames = []enumName[Flip]{
+	{FlipAsShot, "as-shot"},
+	{FlipNone, "none"},
+	{FlipHorizontal, "horizontal"},
+	{FlipVertical, "vertical"},
+	{FlipRotate180, "rotate180"},
+	{FlipTranspose, "transpose"},
+	{FlipRotate270, "rotate270"},
+	{FlipRotate90, "rotate90"},
+	{FlipTransverse, "transverse"},
+}
+
+var outputColorNames = []enumName[OutputColor]{
+	{Raw, "raw"},
+	{SRGB, "srgb"},
+	{AdobeRGB, "adobe"},
+	{WideGamutRGB, "widegamut"},
+	{ProPhotoRGB, "prophoto"},
+	{XYZ, "xyz"},
+	{ACES, "aces"},
+	{DciP3, "dci-p3"},
+	{Rec2020, "rec2020"},
+}
+
+func (q Interpolation) String() string {
+	return enumString(q, interpolationNames, "Interpolation")
+}
+
+func (h HighlightMode) String() string {
+	return enumString(h, highlightNames, "HighlightMode")
+}
+
+func (f FBDDNoiseReduction) String() string {
+	return enumString(f, fbddNames, "FBDDNoiseReduction")
+}
+
+func (m CameraMatrixUsage) String() string {
+	return enumString(m, cameraMatrixNames, "CameraMatrixUsage")
+}
+
+func (f Flip) String() string {
+	return enumString(f, flipNames, "Flip")
+}
+
+func (c OutputColor) String() string {
+	return enumString(c, outputColorNames, "OutputColor")
+}
+
+// ParseInterpolation parses an interpolation name such as "ahd" or its
+// number. Names are case-insensitive, see Interpolation.String.
+func ParseInterpolation(s string) (Interpolation, error) {
+	return parseEnum(s, interpolationNames, "interpolation")
+}
+
+// ParseHighlightMode parses a highlight mode name such as "blend" or its number.
+func ParseHighlightMode(s string) (HighlightMode, error) {
+	return parseEnum(s, highlightNames, "highlight mode")
+}
+
+// ParseFBDDNoiseReduction parses "off", "light", "full" or their number.
+func ParseFBDDNoiseReduction(s string) (FBDDNoiseReduction, error) {
+	return parseEnum(s, fbddNames, "FBDD noise reduction")
+}
+
+// ParseCameraMatrixUsage parses "never", "default", "always" or their number.
+func ParseCameraMatrixUsage(s string) (CameraMatrixUsage, error) {
+	return parseEnum(s, cameraMatrixNames, "camera matrix usage")
+}
+
+// ParseFlip parses a flip name such as "rotate90" or its number.
+func ParseFlip(s string) (Flip, error) {
+	return parseEnum(s, flipNames, "flip")
+}
+
+// ParseOutputColor parses an output color space name such as "srgb" or its number.
+func ParseOutputColor(s string) (OutputColor, error) {
+	return parseEnum(s, outputColorNames, "output color")
+}
+
+// The enums implement encoding.TextMarshaler and encoding.TextUnmarshaler
+// with the names of String, so they read well in config files.
+
+func (q Interpolation) MarshalText() ([]byte, error)      { return marshalEnum(q, interpolationNames) }
+func (h HighlightMode) MarshalText() ([]byte, error)      { return marshalEnum(h, highlightNames) }
+func (f FBDDNoiseReduction) MarshalText() ([]byte, error) { return marshalEnum(f, fbddNames) }
+func (m CameraMatrixUsage) MarshalText() ([]byte, error)  { return marshalEnum(m, cameraMatrixNames) }
+func (f Flip) MarshalText() ([]byte, error)               { return marshalEnum(f, flipNames) }
+func (c OutputColor) MarshalText() ([]byte, error)        { return marshalEnum(c, outputColorNames) }
+
+func (q *Interpolation) UnmarshalText(b []byte) (err error) {
+	*q, err = ParseInterpolation(string(b))
+	return err
+}
+
+func (h *HighlightMode) UnmarshalText(b []byte) (err error) {
+	*h, err = ParseHighlightMode(string(b))
+	return err
+}
+
+func (f *FBDDNoiseReduction) UnmarshalText(b []byte) (err error) {
+	*f, err = ParseFBDDNoiseReduction(string(b))
+	return err
+}
+
+func (m *CameraMatrixUsage) UnmarshalText(b []byte) (err error) {
+	*m, err = ParseCameraMatrixUsage(string(b))
+	return err
+}
+
+func (f *Flip) UnmarshalText(b []byte) (err error) {
+	*f, err = ParseFlip(string(b))
+	return err
+}
+
+func (c *OutputColor) UnmarshalText(b []byte) (err error) {
+	*c, err = ParseOutputColor(string(b))
+	return err
+}
+
+type enumName[T ~int | ~uint8] struct {
+	v    T
+	name string
+}
+
+func lookupEnum[T ~int | ~uint8](v T, names []enumName[T]) (string, bool) {
+	for _, n := range names {
+		if n.v == v {
+			return n.name, true
+		}
+	}
+	return "", false
+}
+
+func enumString[T ~int | ~uint8](v T, names []enumName[T], typ string) string {
+	if name, ok := lookupEnum(v, names); ok {
+		return name
+	}
+	return fmt.Sprintf("%s(%d)", typ, int(v))
+}
+
+func marshalEnum[T ~int | ~uint8](v T, names []enumName[T]) ([]byte, error) {
+	name, ok := lookupEnum(v, names)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal unknown value %d", int(v))
+	}
+	return []byte(name), nil
+}
+
+func parseEnum[T ~int | ~uint8](s string, names []enumName[T], what string) (T, error) {
+	s = strings.TrimSpace(s)
+	for _, n := range names {
+		if strings.EqualFold(n.name, s) {
+			return n.v, nil
+		}
+	}
+	if i, err := strconv.Atoi(s); err == nil && int(T(i)) == i {
+		if _, ok := lookupEnum(T(i), names); ok {
+			return T(i), nil
+		}
+	}
+	valid := make([]string, len(names))
+	for i, n := range names {
+		valid[i] = n.name
+	}
+	return 0, fmt.Errorf("unknown %s %q, want one of %s", what, s, strings.Join(valid, ", "))
+}
@@ -0,0 +1,61 @@
+package golibraw
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEnumNames(t *testing.T) {
+	for _, n := range interpolationNames {
+		if got, err := ParseInterpolation(n.v.String()); err != nil || got != n.v {
+			t.Errorf("ParseInterpolation(%q) = %d, %v", n.v.String(), got, err)
+		}
+	}
+	for _, n := range flipNames {
+		if got, err := ParseFlip(n.v.String()); err != nil || got != n.v {
+			t.Errorf("ParseFlip(%q) = %d, %v", n.v.String(), got, err)
+		}
+	}
+
+	if q, err := ParseInterpolation("AHD"); err != nil || q != InterpolationAHD {
+		t.Errorf("names are not case-insensitive: %d, %v", q, err)
+	}
+	if h, err := ParseHighlightMode("5"); err != nil || h != 5 || h.String() != "rebuild5" {
+		t.Errorf("ParseHighlightMode(\"5\") = %v, %v", h, err)
+	}
+	if _, err := ParseFBDDNoiseReduction("3"); err == nil {
+		t.Error("unknown FBDD value accepted")
+	}
+	if _, err := ParseCameraMatrixUsage("sometimes"); err == nil {
+		t.Error("unknown camera matrix name accepted")
+	}
+	if s := Interpolation(7).String(); s != "Interpolation(7)" {
+		t.Errorf("unknown value String() = %q", s)
+	}
+}
+
+func TestEnumText(t *testing.T) {
+	type config struct {
+		Interpolation Interpolation
+		Flip          Flip
+		Color         OutputColor
+	}
+	data, err := json.Marshal(config{InterpolationDCB, FlipRotate90, ProPhotoRGB})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Interpolation":"dcb","Flip":"rotate90","Color":"prophoto"}`; string(data) != want {
+		t.Errorf("json = %s, want %s", data, want)
+	}
+
+	var c config
+	if err := json.Unmarshal([]byte(`{"Interpolation":"vng","Flip":"as-shot","Color":"rec2020"}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c != (config{InterpolationVNG, FlipAsShot, Rec2020}) {
+		t.Errorf("decoded %+v", c)
+	}
+	if err := json.Unmarshal([]byte(`{"Flip":"sideways"}`), &c); err == nil {
+		t.Error("unknown flip decoded")
+	}
+}
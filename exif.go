@@ -0,0 +1,72 @@
+package golibraw
+
+// #include <stdint.h>
+// #include "libraw/libraw.h"
+//
+// #define GORAW_MAX_EXIF_VALUE (64 << 10)
+//
+// #ifdef __cplusplus
+// extern "C" {
+// #endif
+// void gorawSetExifHandler(libraw_data_t *lr, uintptr_t handle);
+// #ifdef __cplusplus
+// }
+// #endif
+import "C"
+
+import (
+	"runtime/cgo"
+	"unsafe"
+
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// The C handler drops values larger than this before calling into Go.
+const _ = uint(C.GORAW_MAX_EXIF_VALUE-metadata.MaxExifValueSize) + uint(metadata.MaxExifValueSize-C.GORAW_MAX_EXIF_VALUE)
+
+// exifCollector receives the tags LibRaw parses while opening a file.
+type exifCollector struct {
+	handle cgo.Handle
+	proc   *C.libraw_data_t
+	tags   metadata.ExifTags
+}
+
+// collectExifTags registers an EXIF handler on proc if CollectExifTags is
+// set. The returned collector must be stopped once the file is open; a
+// nil collector is valid and collects nothing.
+func (p *Processor) collectExifTags(proc *C.libraw_data_t) *exifCollector {
+	if !p.options.CollectExifTags {
+		return nil
+	}
+	c := &exifCollector{proc: proc}
+	c.handle = cgo.NewHandle(c)
+	C.gorawSetExifHandler(proc, C.uintptr_t(c.handle))
+	return c
+}
+
+// stop unregisters the handler and returns the collected tags.
+func (c *exifCollector) stop() metadata.ExifTags {
+	if c == nil {
+		return nil
+	}
+	C.gorawSetExifHandler(c.proc, 0)
+	c.handle.Delete()
+	return c.tags
+}
+
+//export goLibrawExifTag
+func goLibrawExifTag(handle C.uintptr_t, tag, typ, count C.int, ord C.uint, data *C.char, n C.int) {
+	c := cgo.Handle(handle).Value().(*exifCollector)
+
+	t := metadata.ExifTag{
+		IFD:       uint16(uint32(tag) >> 16),
+		ID:        uint16(tag),
+		Type:      uint16(typ),
+		Count:     uint32(count),
+		BigEndian: ord == 0x4d4d,
+	}
+	if data != nil {
+		t.Value = C.GoBytes(unsafe.Pointer(data), n)
+	}
+	c.tags = append(c.tags, t)
+}
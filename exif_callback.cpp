@@ -0,0 +1,47 @@
+// EXIF parser callback for LibRaw. It is written in C++ because the value
+// bytes can only be read through the LibRaw_abstract_datastream the parser
+// passes in.
+
+#include "libraw/libraw.h"
+#include "_cgo_export.h"
+
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+
+static const int tiffTypeSize[] = {0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8, 4};
+
+static void gorawExifCallback(void *context, int tag, int type, int len, unsigned int ord, void *ifp, INT64 base)
+{
+  (void)base;
+  LibRaw_abstract_datastream *stream = (LibRaw_abstract_datastream *)ifp;
+
+  long long size = 0;
+  if (type > 0 && type < (int)(sizeof(tiffTypeSize) / sizeof(tiffTypeSize[0])) && len > 0)
+    size = (long long)len * tiffTypeSize[type];
+
+  // The stream is positioned at the value; values that are too large are
+  // reported without their bytes.
+  char *buf = NULL;
+  int n = 0;
+  if (stream && size > 0 && size <= GORAW_MAX_EXIF_VALUE)
+  {
+    buf = (char *)malloc((size_t)size);
+    if (buf)
+    {
+      INT64 pos = stream->tell();
+      n = stream->read(buf, 1, (size_t)size);
+      stream->seek(pos, SEEK_SET);
+      if (n < 0)
+        n = 0;
+    }
+  }
+
+  goLibrawExifTag((uintptr_t)context, tag, type, len, ord, buf, n);
+  free(buf);
+}
+
+extern "C" void gorawSetExifHandler(libraw_data_t *lr, uintptr_t handle)
+{
+  libraw_set_exifparser_handler(lr, handle ? gorawExifCallback : NULL, (void *)handle);
+}
@@ -0,0 +1,39 @@
+package golibraw
+
+import (
+	"math"
+	"testing"
+)
+
+// TestCollectExifTags checks that the tags reported by the EXIF callback
+// agree with the values LibRaw decodes itself.
+func TestCollectExifTags(t *testing.T) {
+	opts := NewProcessorOptions()
+	opts.CollectExifTags = true
+	processor := NewProcessor(opts)
+
+	for _, path := range getAllFilesInTestDir() {
+		_, meta, err := processor.UnpackRaw(path)
+		if err != nil {
+			t.Fatalf("UnpackRaw failed: %v", err)
+		}
+		if len(meta.ExifTags) == 0 {
+			t.Errorf("no EXIF tags collected for '%s'", path)
+			continue
+		}
+
+		if tag, ok := meta.ExifTags.Find(0, 0x829a); ok { // ExposureTime
+			if v := tag.Floats(); len(v) != 1 || math.Abs(v[0]-float64(meta.Other.Shutter)) > 0.02*v[0] {
+				t.Errorf("ExposureTime tag = %v, LibRaw decoded %v for '%s'", v, meta.Other.Shutter, path)
+			}
+		}
+	}
+
+	_, meta, err := NewProcessor(NewProcessorOptions()).UnpackRaw(getAllFilesInTestDir()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.ExifTags != nil {
+		t.Error("EXIF tags collected although CollectExifTags is not set")
+	}
+}
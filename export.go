@@ -0,0 +1,148 @@
+package golibraw
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"os"
+
+	"github.com/stmtc233/go-libraw/pkg/export"
+	"github.com/stmtc233/go-libraw/pkg/icc"
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// ColorSpace returns the primaries and white point LibRaw renders into for
+// this OutputColor. Raw output has no defined color space.
+func (c OutputColor) ColorSpace() (icc.ColorSpace, bool) {
+	switch c {
+	case SRGB:
+		return icc.SRGBSpace, true
+	case AdobeRGB:
+		return icc.AdobeRGBSpace, true
+	case WideGamutRGB:
+		return icc.WideGamutRGBSpace, true
+	case ProPhotoRGB:
+		return icc.ProPhotoRGBSpace, true
+	case XYZ:
+		return icc.XYZSpace, true
+	case ACES:
+		return icc.ACESSpace, true
+	case DciP3:
+		return icc.DCIP3Space, true
+	case Rec2020:
+		return icc.Rec2020Space, true
+	}
+	return icc.ColorSpace{}, false
+}
+
+// ICCProfile returns the ICC profile describing images rendered with these
+// options. If OutputProfile is set LibRaw converts into that profile, so
+// its contents are returned; otherwise a profile is built from OutputColor
+// and the Gamm curve. Raw output has no profile and yields nil.
+func (opts *ProcessorOptions) ICCProfile() ([]byte, error) {
+	if opts.OutputProfile != "" {
+		return os.ReadFile(opts.OutputProfile)
+	}
+
+	cs, ok := opts.OutputColor.ColorSpace()
+	if !ok {
+		return nil, nil
+	}
+	return icc.Profile(cs, icc.NewToneCurve(opts.Gamm[0], opts.Gamm[1])), nil
+}
+
+// UseStandardCurve sets Gamm to the tone curve OutputColor is defined with
+// (e.g. the sRGB curve for SRGB, gamma 1.8 for ProPhotoRGB), so rendered
+// images are encoded exactly as their standard color space specifies.
+// Raw output keeps its curve.
+func (opts *ProcessorOptions) UseStandardCurve() {
+	if cs, ok := opts.OutputColor.ColorSpace(); ok {
+		opts.Gamm = cs.Curve.Gamm()
+	}
+}
+
+// Rendered is a processed image together with its metadata and the color
+// space its pixels are encoded in.
+type Rendered struct {
+	Image    image.Image
+	Metadata metadata.ImgMetadata
+
+	OutputColor OutputColor
+	// ColorSpace and Curve describe the encoding. ColorSpace is the zero
+	// value for Raw output and for custom output profiles.
+	ColorSpace icc.ColorSpace
+	Curve      icc.ToneCurve
+	// ICCProfile describes the encoding for color managed applications;
+	// it is nil for Raw output.
+	ICCProfile []byte
+}
+
+// Render processes a RAW file like ProcessRaw and also reports the color
+// space of the result.
+func (p *Processor) Render(filepath string) (*Rendered, error) {
+	img, meta, err := p.ProcessRaw(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := p.options.ICCProfile()
+	if err != nil {
+		return nil, fmt.Errorf("read output profile: %v", err)
+	}
+
+	r := &Rendered{
+		Image:       img,
+		Metadata:    meta,
+		OutputColor: p.options.OutputColor,
+		Curve:       icc.NewToneCurve(p.options.Gamm[0], p.options.Gamm[1]),
+		ICCProfile:  profile,
+	}
+	if p.options.OutputProfile == "" {
+		r.ColorSpace, _ = p.options.OutputColor.ColorSpace()
+	}
+	return r, nil
+}
+
+// EncodeJPEG writes the image as a JPEG with its ICC profile and the EXIF
+// metadata of the file embedded.
+func (r *Rendered) EncodeJPEG(w io.Writer, quality int) error {
+	return export.EncodeJPEGWithOptions(w, r.Image, &export.JPEGOptions{
+		Quality:    quality,
+		ICCProfile: r.ICCProfile,
+		Metadata:   &r.Metadata,
+	})
+}
+
+// EncodePNG writes the image as a PNG with its ICC profile embedded.
+func (r *Rendered) EncodePNG(w io.Writer) error {
+	return export.EncodePNG(w, r.Image, r.ICCProfile)
+}
+
+// EncodeTIFF writes the image as a TIFF. When opts.ICCProfile is empty the
+// profile of the image is embedded, and opts.Metadata defaults to the
+// metadata of the file.
+func (r *Rendered) EncodeTIFF(w io.Writer, opts *export.TIFFOptions) error {
+	tiffOpts := export.TIFFOptions{}
+	if opts != nil {
+		tiffOpts = *opts
+	}
+	if tiffOpts.ICCProfile == nil {
+		tiffOpts.ICCProfile = r.ICCProfile
+	}
+	if tiffOpts.Metadata == nil {
+		tiffOpts.Metadata = &r.Metadata
+	}
+	return export.EncodeTIFF(w, r.Image, &tiffOpts)
+}
+
+// ExportTIFF processes a RAW file and writes it to w as a baseline TIFF.
+// The bit depth follows OutputBps unless opts.Bits is set. When
+// opts.ICCProfile is empty the profile matching the processor options is
+// embedded, and opts.Metadata defaults to the metadata of the file.
+func (p *Processor) ExportTIFF(w io.Writer, filepath string, opts *export.TIFFOptions) error {
+	r, err := p.Render(filepath)
+	if err != nil {
+		return err
+	}
+	return r.EncodeTIFF(w, opts)
+}
@@ -0,0 +1,44 @@
+package golibraw
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stmtc233/go-libraw/pkg/export"
+)
+
+// TestPPMMatchesLibRaw checks that ProcessRaw + EncodePPM produces the same
+// bytes as LibRaw's own PPM writer for 8 and 16-bit output.
+func TestPPMMatchesLibRaw(t *testing.T) {
+	for _, bps := range []int{8, 16} {
+		opts := NewProcessorOptions()
+		opts.OutputBps = bps
+		processor := NewProcessor(opts)
+
+		for _, path := range getAllFilesInTestDir() {
+			ref := filepath.Join(t.TempDir(), "ref.ppm")
+			if err := processor.ProcessToFile(path, ref); err != nil {
+				t.Fatalf("ProcessToFile failed: %v", err)
+			}
+			want, err := os.ReadFile(ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img, _, err := processor.ProcessRaw(path)
+			if err != nil {
+				t.Fatalf("ProcessRaw failed: %v", err)
+			}
+			var got bytes.Buffer
+			if err := export.EncodePNM(&got, img, bps); err != nil {
+				t.Fatalf("EncodePNM failed: %v", err)
+			}
+
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("%d-bit PPM for '%s' differs from LibRaw output", bps, path)
+			}
+		}
+	}
+}
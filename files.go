@@ -0,0 +1,23 @@
+package golibraw
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// RawExtensions lists the file extensions of the RAW formats LibRaw reads,
+// in lower case with the leading dot. Tools walking directories use it to
+// pick the files worth opening.
+var RawExtensions = []string{
+	".3fr", ".ari", ".arw", ".bay", ".cap", ".cr2", ".cr3", ".crw", ".cs1", ".dc2",
+	".dcr", ".dcs", ".dng", ".drf", ".eip", ".erf", ".fff", ".gpr", ".iiq", ".k25",
+	".kc2", ".kdc", ".mdc", ".mef", ".mos", ".mrw", ".nef", ".nrw", ".orf", ".ori",
+	".pef", ".ptx", ".pxn", ".qtk", ".r3d", ".raf", ".raw", ".rdc", ".rw2", ".rwl",
+	".rwz", ".sr2", ".srf", ".srw", ".sti", ".x3f",
+}
+
+// IsRawFile reports whether path has one of the RawExtensions, in any case.
+func IsRawFile(path string) bool {
+	return slices.Contains(RawExtensions, strings.ToLower(filepath.Ext(path)))
+}
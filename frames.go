@@ -0,0 +1,116 @@
+package golibraw
+
+// #include "libraw/libraw.h"
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"iter"
+
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// Frame is one image of a file holding several, e.g. the two frames of a
+// Fujifilm Super CCD, the sub-frames of a Pentax pixel shift or Sinar
+// multi-shot capture, or the images of a DNG with RawDNGAddEnhanced.
+type Frame struct {
+	Index    int
+	Image    image.Image // set by ProcessFrames
+	Raw      *RawImage   // set by UnpackFrames
+	Metadata metadata.ImgMetadata
+}
+
+// FrameCount opens a file and returns the number of frames LibRaw can
+// decode from it (LibRawIData.RawCount). The raw processing flags of
+// Unpack are applied, since some of them add frames.
+func (p *Processor) FrameCount(filepath string) (int, error) {
+	if p.invalid != nil {
+		return 0, p.invalid
+	}
+
+	proc := C.libraw_init(0)
+	if proc == nil {
+		return 0, fmt.Errorf("failed to initialize libraw")
+	}
+	defer func() {
+		C.libraw_recycle(proc)
+		C.libraw_close(proc)
+	}()
+
+	proc.rawparams = p.options.Unpack.Apply(proc.rawparams)
+	defer p.options.Unpack.Free(proc.rawparams)
+
+	cFile := C.CString(filepath)
+	defer freeCString(cFile)
+
+	if err := librawErr(C.libraw_open_file(proc, cFile)); err != nil {
+		return 0, err
+	}
+	return max(int(proc.idata.raw_count), 1), nil
+}
+
+// frame returns a Processor with the options of p that decodes frame i.
+func (p *Processor) frame(i int) *Processor {
+	opts := p.options
+	if opts.Unpack.IsZero() {
+		opts.Unpack = DefaultUnpackOptions()
+	}
+	opts.Unpack = opts.Unpack.clone()
+	opts.Unpack.ShotSelect = uint(i)
+	return p.derive(opts)
+}
+
+// ProcessFrame is ProcessRaw for frame i of a file, overriding
+// Unpack.ShotSelect.
+func (p *Processor) ProcessFrame(filepath string, i int) (image.Image, metadata.ImgMetadata, error) {
+	return p.frame(i).ProcessRaw(filepath)
+}
+
+// UnpackFrame is UnpackRaw for frame i of a file, overriding
+// Unpack.ShotSelect.
+func (p *Processor) UnpackFrame(filepath string, i int) (*RawImage, metadata.ImgMetadata, error) {
+	return p.frame(i).UnpackRaw(filepath)
+}
+
+// ProcessFrames processes every frame of a file in turn. Only one frame
+// is held in memory at a time; iteration stops after the first error.
+//
+//	for frame, err := range p.ProcessFrames(path) {
+//		if err != nil { ... }
+//		save(frame.Index, frame.Image)
+//	}
+func (p *Processor) ProcessFrames(filepath string) iter.Seq2[Frame, error] {
+	return p.frames(filepath, func(i int) (Frame, error) {
+		img, meta, err := p.ProcessFrame(filepath, i)
+		return Frame{Index: i, Image: img, Metadata: meta}, err
+	})
+}
+
+// UnpackFrames unpacks the CFA data of every frame of a file in turn, see
+// ProcessFrames.
+func (p *Processor) UnpackFrames(filepath string) iter.Seq2[Frame, error] {
+	return p.frames(filepath, func(i int) (Frame, error) {
+		raw, meta, err := p.UnpackFrame(filepath, i)
+		return Frame{Index: i, Raw: raw, Metadata: meta}, err
+	})
+}
+
+func (p *Processor) frames(filepath string, decode func(i int) (Frame, error)) iter.Seq2[Frame, error] {
+	return func(yield func(Frame, error) bool) {
+		n, err := p.FrameCount(filepath)
+		if err != nil {
+			yield(Frame{}, err)
+			return
+		}
+		for i := range n {
+			frame, err := decode(i)
+			if err != nil {
+				err = fmt.Errorf("frame %d: %w", i, err)
+			}
+			if !yield(frame, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package golibraw
+
+import "testing"
+
+func TestFrames(t *testing.T) {
+	opts := NewProcessorOptions()
+	opts.HalfSize = true
+	p := NewProcessor(opts)
+
+	for _, path := range getAllFilesInTestDir() {
+		n, err := p.FrameCount(path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+
+		var got int
+		for frame, err := range p.UnpackFrames(path) {
+			if err != nil {
+				// not every file has CFA data, see UnpackRaw
+				t.Logf("%s: %v", path, err)
+				break
+			}
+			if frame.Index != got || frame.Raw == nil {
+				t.Errorf("%s: frame %d returned as %d", path, got, frame.Index)
+			}
+			got++
+		}
+		if got != 0 && got != n {
+			t.Errorf("%s: unpacked %d of %d frames", path, got, n)
+		}
+
+		if _, _, err := p.ProcessFrame(path, n); err == nil {
+			t.Errorf("%s: frame %d of %d decoded", path, n, n)
+		}
+	}
+}
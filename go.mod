@@ -1,3 +1,9 @@
 module github.com/stmtc233/go-libraw
 
 go 1.23.0
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	golang.org/x/image v0.25.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,257 @@
+package golibraw
+
+// #include "libraw/libraw.h"
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// FileInfo is what LibRaw reports about a file without processing it, as
+// printed by LibRaw's raw-identify.
+type FileInfo struct {
+	Metadata metadata.ImgMetadata `json:"metadata"`
+
+	Filters       uint32  `json:"filters"`        // LibRaw CFA bitmask, 9 for X-Trans, 0 for none
+	FilterPattern string  `json:"filter_pattern"` // CFA colors as letters of the color description, see cfaPattern
+	PixelAspect   float64 `json:"pixel_aspect"`   // width of a pixel relative to its height
+	ICCProfileLen int     `json:"icc_profile_length"`
+
+	Thumbnails []ThumbnailInfo `json:"thumbnails"`
+	Warnings   Warnings        `json:"warnings"`
+
+	// Unpacked tells whether the raw data was decoded, which fills in the
+	// values only known after decoding (e.g. Color.DataMaximum).
+	Unpacked bool `json:"unpacked"`
+}
+
+// ThumbnailKind is the encoding of an embedded preview as stored in the
+// file (LibRaw_internal_thumbnail_formats).
+type ThumbnailKind int
+
+const (
+	ThumbKindUnknown    ThumbnailKind = 0 // LIBRAW_INTERNAL_THUMBNAIL_UNKNOWN
+	ThumbKindKodakThumb ThumbnailKind = 1 // LIBRAW_INTERNAL_THUMBNAIL_KODAK_THUMB
+	ThumbKindKodakYCbCr ThumbnailKind = 2 // LIBRAW_INTERNAL_THUMBNAIL_KODAK_YCBCR
+	ThumbKindKodakRGB   ThumbnailKind = 3 // LIBRAW_INTERNAL_THUMBNAIL_KODAK_RGB
+	ThumbKindJPEG       ThumbnailKind = 4 // LIBRAW_INTERNAL_THUMBNAIL_JPEG
+	ThumbKindLayer      ThumbnailKind = 5 // LIBRAW_INTERNAL_THUMBNAIL_LAYER
+	ThumbKindRollei     ThumbnailKind = 6 // LIBRAW_INTERNAL_THUMBNAIL_ROLLEI
+	ThumbKindPPM        ThumbnailKind = 7 // LIBRAW_INTERNAL_THUMBNAIL_PPM
+	ThumbKindPPM16      ThumbnailKind = 8 // LIBRAW_INTERNAL_THUMBNAIL_PPM16
+	ThumbKindX3F        ThumbnailKind = 9 // LIBRAW_INTERNAL_THUMBNAIL_X3F
+)
+
+var thumbKindNames = [...]string{"unknown", "kodak-thumb", "kodak-ycbcr", "kodak-rgb", "jpeg", "layer", "rollei", "ppm", "ppm16", "x3f"}
+
+func (k ThumbnailKind) String() string {
+	if k >= 0 && int(k) < len(thumbKindNames) {
+		return thumbKindNames[k]
+	}
+	return fmt.Sprintf("ThumbnailKind(%d)", int(k))
+}
+
+func (k ThumbnailKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// ThumbnailInfo describes one embedded preview (libraw_thumbnail_item_t).
+type ThumbnailInfo struct {
+	Kind   ThumbnailKind `json:"kind"`
+	Width  int           `json:"width"`
+	Height int           `json:"height"`
+	Flip   int           `json:"flip"`   // LibRaw rotation code, see LibRawSizes.Flip
+	Length int           `json:"length"` // size in the file, in bytes
+	Offset int64         `json:"offset"`
+	Bits   int           `json:"bits"`   // bits per sample of bitmap previews
+	Colors int           `json:"colors"` // samples per pixel of bitmap previews
+}
+
+// Warnings is a set of LibRaw process warnings.
+type Warnings uint32
+
+const (
+	WarnBadCameraWB         Warnings = 1 << 2  // LIBRAW_WARN_BAD_CAMERA_WB
+	WarnNoMetadata          Warnings = 1 << 3  // LIBRAW_WARN_NO_METADATA
+	WarnNoJPEGLib           Warnings = 1 << 4  // LIBRAW_WARN_NO_JPEGLIB
+	WarnNoEmbeddedProfile   Warnings = 1 << 5  // LIBRAW_WARN_NO_EMBEDDED_PROFILE
+	WarnNoInputProfile      Warnings = 1 << 6  // LIBRAW_WARN_NO_INPUT_PROFILE
+	WarnBadOutputProfile    Warnings = 1 << 7  // LIBRAW_WARN_BAD_OUTPUT_PROFILE
+	WarnNoBadPixelMap       Warnings = 1 << 8  // LIBRAW_WARN_NO_BADPIXELMAP
+	WarnBadDarkFrameFile    Warnings = 1 << 9  // LIBRAW_WARN_BAD_DARKFRAME_FILE
+	WarnBadDarkFrameDim     Warnings = 1 << 10 // LIBRAW_WARN_BAD_DARKFRAME_DIM
+	WarnRawSpeedProblem     Warnings = 1 << 12 // LIBRAW_WARN_RAWSPEED_PROBLEM
+	WarnRawSpeedUnsupported Warnings = 1 << 13 // LIBRAW_WARN_RAWSPEED_UNSUPPORTED
+	WarnRawSpeedProcessed   Warnings = 1 << 14 // LIBRAW_WARN_RAWSPEED_PROCESSED
+	WarnFallbackToAHD       Warnings = 1 << 15 // LIBRAW_WARN_FALLBACK_TO_AHD
+	WarnParseFujiProcessed  Warnings = 1 << 16 // LIBRAW_WARN_PARSEFUJI_PROCESSED
+	WarnDNGSDKProcessed     Warnings = 1 << 17 // LIBRAW_WARN_DNGSDK_PROCESSED
+	WarnDNGImagesReordered  Warnings = 1 << 18 // LIBRAW_WARN_DNG_IMAGES_REORDERED
+	WarnDNGStage2Applied    Warnings = 1 << 19 // LIBRAW_WARN_DNG_STAGE2_APPLIED
+	WarnDNGStage3Applied    Warnings = 1 << 20 // LIBRAW_WARN_DNG_STAGE3_APPLIED
+)
+
+var warningNames = map[Warnings]string{
+	WarnBadCameraWB:         "bad-camera-wb",
+	WarnNoMetadata:          "no-metadata",
+	WarnNoJPEGLib:           "no-jpeglib",
+	WarnNoEmbeddedProfile:   "no-embedded-profile",
+	WarnNoInputProfile:      "no-input-profile",
+	WarnBadOutputProfile:    "bad-output-profile",
+	WarnNoBadPixelMap:       "no-badpixelmap",
+	WarnBadDarkFrameFile:    "bad-darkframe-file",
+	WarnBadDarkFrameDim:     "bad-darkframe-dim",
+	WarnRawSpeedProblem:     "rawspeed-problem",
+	WarnRawSpeedUnsupported: "rawspeed-unsupported",
+	WarnRawSpeedProcessed:   "rawspeed-processed",
+	WarnFallbackToAHD:       "fallback-to-ahd",
+	WarnParseFujiProcessed:  "parsefuji-processed",
+	WarnDNGSDKProcessed:     "dngsdk-processed",
+	WarnDNGImagesReordered:  "dng-images-reordered",
+	WarnDNGStage2Applied:    "dng-stage2-applied",
+	WarnDNGStage3Applied:    "dng-stage3-applied",
+}
+
+// Names returns the names of the warnings in w, lowest bit first. Unknown
+// bits are named by their value.
+func (w Warnings) Names() []string {
+	names := []string{}
+	for w != 0 {
+		bit := Warnings(1) << bits.TrailingZeros32(uint32(w))
+		w &^= bit
+		if name, ok := warningNames[bit]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("0x%x", uint32(bit)))
+		}
+	}
+	return names
+}
+
+func (w Warnings) String() string {
+	if w == 0 {
+		return "none"
+	}
+	return strings.Join(w.Names(), ", ")
+}
+
+// MarshalJSON encodes the warnings as a list of names.
+func (w Warnings) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.Names())
+}
+
+// cfaPattern spells out a color filter array like dcraw -i -v: 16 letters
+// for a Bayer pattern (8 rows of 2 columns), six rows separated by '/' for
+// X-Trans, and "" without a CFA.
+func cfaPattern(cdesc [5]rune, filters uint32, xtrans [6][6]uint8) string {
+	letter := func(c int) rune {
+		if c >= 0 && c < len(cdesc) && cdesc[c] != 0 {
+			return cdesc[c]
+		}
+		return '?'
+	}
+	r := &RawImage{Filters: filters, XTrans: xtrans}
+	var b strings.Builder
+	switch {
+	case filters == 9:
+		for row := range 6 {
+			if row > 0 {
+				b.WriteByte('/')
+			}
+			for col := range 6 {
+				b.WriteRune(letter(r.CFAColor(row, col)))
+			}
+		}
+	case filters >= 1000:
+		for i := range 16 {
+			b.WriteRune(letter(r.CFAColor(i>>1, i&1)))
+		}
+	}
+	return b.String()
+}
+
+// Identify opens a file and reports its metadata, embedded previews and
+// LibRaw warnings. With unpack set the raw data is decoded as well, which
+// is slower but completes the color data and may raise more warnings.
+func (p *Processor) Identify(filepath string, unpack bool) (*FileInfo, error) {
+	if p.invalid != nil {
+		return nil, p.invalid
+	}
+	if err := p.ctxErr(); err != nil {
+		return nil, err
+	}
+
+	proc := C.libraw_init(0)
+	if proc == nil {
+		return nil, fmt.Errorf("failed to initialize libraw")
+	}
+	defer func() {
+		C.libraw_recycle(proc)
+		C.libraw_close(proc)
+	}()
+
+	proc.params = p.options.Apply(proc.params)
+	defer p.options.Free(proc.params)
+	proc.rawparams = p.options.Unpack.Apply(proc.rawparams)
+	defer p.options.Unpack.Free(proc.rawparams)
+	watch := p.watchContext(proc)
+	defer watch.stop()
+
+	cFile := C.CString(filepath)
+	defer freeCString(cFile)
+
+	collector := p.collectExifTags(proc)
+	err := librawErr(C.libraw_open_file(proc, cFile))
+	exifTags := collector.stop()
+	if err != nil {
+		return nil, err
+	}
+	if unpack {
+		if err := watch.err(librawErr(C.libraw_unpack(proc))); err != nil {
+			return nil, err
+		}
+	}
+
+	info := &FileInfo{
+		Metadata:      readMetadata(proc),
+		Filters:       uint32(proc.idata.filters),
+		PixelAspect:   float64(proc.sizes.pixel_aspect),
+		ICCProfileLen: int(proc.color.profile_length),
+		Warnings:      Warnings(proc.process_warnings),
+		Unpacked:      unpack,
+	}
+	info.Metadata.ExifTags = exifTags
+	if !unpack {
+		// the copy readMetadata uses is only saved by libraw_unpack
+		info.Metadata.Color = readColorData(&proc.color)
+	}
+
+	var xtrans [6][6]uint8
+	for i := range 6 {
+		for j := range 6 {
+			xtrans[i][j] = uint8(proc.idata.xtrans[i][j])
+		}
+	}
+	info.FilterPattern = cfaPattern(info.Metadata.IData.ColorDescription, info.Filters, xtrans)
+
+	list := &proc.thumbs_list
+	for i := range min(int(list.thumbcount), len(list.thumblist)) {
+		t := &list.thumblist[i]
+		info.Thumbnails = append(info.Thumbnails, ThumbnailInfo{
+			Kind:   ThumbnailKind(t.tformat),
+			Width:  int(t.twidth),
+			Height: int(t.theight),
+			Flip:   int(t.tflip),
+			Length: int(t.tlength),
+			Offset: int64(t.toffset),
+			Bits:   int(t.tmisc & 31),
+			Colors: int(t.tmisc >> 5),
+		})
+	}
+	return info, nil
+}
@@ -0,0 +1,86 @@
+package golibraw
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestIdentify(t *testing.T) {
+	p := NewProcessor(NewProcessorOptions())
+
+	for _, path := range getAllFilesInTestDir() {
+		info, err := p.Identify(path, false)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if info.Metadata.IData.Make == "" || info.Metadata.Sizes.Width == 0 {
+			t.Errorf("%s: no camera or size identified", path)
+		}
+		for i, thumb := range info.Thumbnails {
+			if thumb.Length <= 0 {
+				t.Errorf("%s: thumbnail %d has length %d", path, i, thumb.Length)
+			}
+		}
+
+		unpacked, err := p.Identify(path, true)
+		if err != nil {
+			t.Logf("%s: unpack: %v", path, err)
+			continue
+		}
+		if unpacked.Metadata.Color.Maximum == 0 || unpacked.FilterPattern != info.FilterPattern {
+			t.Errorf("%s: unpacked info differs: maximum %d, pattern %q vs %q", path,
+				unpacked.Metadata.Color.Maximum, unpacked.FilterPattern, info.FilterPattern)
+		}
+	}
+}
+
+func TestCFAPattern(t *testing.T) {
+	rgbg := [5]rune{'R', 'G', 'B', 'G'}
+	if got := cfaPattern(rgbg, 0x94949494, [6][6]uint8{}); got != "RGGBRGGBRGGBRGGB" {
+		t.Errorf("RGGB pattern %q", got)
+	}
+	if got := cfaPattern(rgbg, 0, [6][6]uint8{}); got != "" {
+		t.Errorf("pattern without CFA %q", got)
+	}
+
+	xtrans := [6][6]uint8{
+		{1, 1, 0, 1, 1, 2},
+		{1, 1, 2, 1, 1, 0},
+		{2, 0, 1, 0, 2, 1},
+		{1, 1, 2, 1, 1, 0},
+		{1, 1, 0, 1, 1, 2},
+		{0, 2, 1, 2, 0, 1},
+	}
+	if got := cfaPattern([5]rune{'R', 'G', 'B'}, 9, xtrans); got != "GGRGGB/GGBGGR/BRGRBG/GGBGGR/GGRGGB/RBGBRG" {
+		t.Errorf("X-Trans pattern %q", got)
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	w := WarnBadCameraWB | WarnFallbackToAHD | 1<<30
+	if got := w.String(); got != "bad-camera-wb, fallback-to-ahd, 0x40000000" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := Warnings(0).String(); got != "none" {
+		t.Errorf("no warnings: %q", got)
+	}
+	data, err := json.Marshal(Warnings(0))
+	if err != nil || string(data) != "[]" {
+		t.Errorf("JSON %s, %v", data, err)
+	}
+}
+
+func TestIsRawFile(t *testing.T) {
+	for path, want := range map[string]bool{
+		"a/IMG_0001.CR2": true,
+		"b.nef":          true,
+		"c.Dng":          true,
+		"d.jpg":          false,
+		"raw":            false,
+	} {
+		if got := IsRawFile(path); got != want {
+			t.Errorf("IsRawFile(%q) = %v", path, got)
+		}
+	}
+}
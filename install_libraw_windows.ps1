@@ -67,7 +67,5 @@ $AbsInstallDir = $InstallDir -replace "\\", "/"
 Write-Host "`n安装完成!"
 Write-Host "头文件位置: $AbsInstallDir/include"
 Write-Host "库文件位置: $AbsInstallDir/lib"
-Write-Host "`n请修改 libraw.go 文件中的 cgo 指令："
-Write-Host "// #cgo CFLAGS: -I${AbsInstallDir}/include" -ForegroundColor Green
-Write-Host "// #cgo LDFLAGS: -L${AbsInstallDir}/lib -lraw -lws2_32" -ForegroundColor Green
-# 注意：在 Windows 上通常需要链接 ws2_32
+Write-Host "`n使用 libraw_static 构建标签进行静态链接："
+Write-Host "go build -tags libraw_static ." -ForegroundColor Green
@@ -3,15 +3,15 @@
 // inside a configurable Processor type.
 package golibraw
 
-// #cgo LDFLAGS: -lraw -lws2_32 -lstdc++
 // #include "libraw/libraw.h"
 // #include <stdlib.h>
 import "C"
 
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
 	"image"
-	"time"
 	"unsafe"
 
 	"github.com/stmtc233/go-libraw/pkg/metadata"
@@ -75,10 +75,10 @@ type ProcessorOptions struct {
 
 	HalfSize        bool // output image at 50% size
 	FourColorRGB    bool // switches on separate interpolations for two green components
-	Highlight       int  // 0-9: Highlight mode (0=clip, 1=unclip, 2=blend, 3+=rebuild)
+	Highlight       HighlightMode
 	UseAutoWb       bool
 	UseCameraWb     bool
-	UseCameraMatrix int
+	UseCameraMatrix CameraMatrixUsage
 
 	OutputColor OutputColor
 
@@ -87,11 +87,11 @@ type ProcessorOptions struct {
 	BadPixels     string // path to bad pixels map file
 	DarkFrame     string // path to dark frame file
 
-	OutputBps        int // 8 or 16
-	OutputTiff       bool
-	OutputFlags      int // Bitfield that allows to set output file options
-	UserFlip         int // EXIF rotation flags -> 0 = no rotation
-	UserQual         int // Interpolaton -> 0 = Linear, 1 = VNG, 2 = PPG, 3 = AHD
+	OutputBps        int  // 8 or 16
+	OutputTiff       bool // write TIFF instead of PPM in ProcessToFile, see ExportTIFF for Go TIFF output
+	OutputFlags      int  // Bitfield that allows to set output file options
+	UserFlip         Flip
+	UserQual         Interpolation
 	UserBlack        int
 	UserCblack       [4]int  // per-channel black level offsets
 	UserSat          int     // Saturation
@@ -103,12 +103,16 @@ type ProcessorOptions struct {
 	GreenMatching    bool    // Enable green channel equalization
 	DcbIterations    int
 	DcbEnhanceFl     bool
-	FbddNoiserd      int // 0 = do not use, 1 = light reduction, 2 full reduction
+	FbddNoiserd      FBDDNoiseReduction
 	ExpCorrect       bool
 	ExpShift         float32
 	ExpPreser        float32
 	NoAutoScale      bool
 	NoInterpolation  bool
+
+	CollectExifTags bool // record every tag LibRaw parses in ImgMetadata.ExifTags
+
+	Unpack UnpackOptions // decoder settings, applied when the file is opened
 }
 
 func (opts *ProcessorOptions) bool(v bool) C.int {
@@ -233,12 +237,12 @@ func NewProcessorOptions() ProcessorOptions {
 
 		HalfSize:        false,
 		FourColorRGB:    false,
-		Highlight:       0,
+		Highlight:       HighlightClip,
 		UseAutoWb:       false,
 		UseCameraWb:     false,
-		UseCameraMatrix: 1,
+		UseCameraMatrix: CameraMatrixDefault,
 
-		OutputColor:   1,
+		OutputColor:   SRGB,
 		OutputProfile: "",
 		CameraProfile: "",
 		BadPixels:     "",
@@ -247,8 +251,8 @@ func NewProcessorOptions() ProcessorOptions {
 		OutputBps:   8,
 		OutputTiff:  false,
 		OutputFlags: 0,
-		UserFlip:    -1,
-		UserQual:    -1,
+		UserFlip:    FlipAsShot,
+		UserQual:    InterpolationDefault,
 		UserBlack:   -1,
 		UserCblack:  [4]int{0, 0, 0, 0},
 		UserSat:     -1,
@@ -261,12 +265,16 @@ func NewProcessorOptions() ProcessorOptions {
 		GreenMatching:    false,
 		DcbIterations:    0,
 		DcbEnhanceFl:     false,
-		FbddNoiserd:      0,
+		FbddNoiserd:      FBDDOff,
 		ExpCorrect:       false,
 		ExpShift:         1.0,
 		ExpPreser:        0.0,
 		NoAutoScale:      false,
 		NoInterpolation:  false,
+
+		CollectExifTags: false,
+
+		Unpack: DefaultUnpackOptions(),
 	}
 }
 
@@ -274,11 +282,15 @@ func NewProcessorOptions() ProcessorOptions {
 // Each method creates its own libraw processor so that calls are goroutine‐safe.
 type Processor struct {
 	options ProcessorOptions
+	invalid error           // result of options.Validate, returned by every processing call
+	ctx     context.Context // see WithContext
 	// TODO: add pool.Sync
 }
 
+// NewProcessor creates a Processor. Invalid options are reported by the
+// processing methods, see ProcessorOptions.Validate.
 func NewProcessor(opts ProcessorOptions) *Processor {
-	return &Processor{options: opts}
+	return &Processor{options: opts, invalid: opts.Validate()}
 }
 
 func freeCString(s *C.char) {
@@ -325,8 +337,17 @@ func clearAndClose(proc *C.libraw_data_t, memImg *C.libraw_processed_image_t) {
 //   - proc: the libraw processor pointer
 //   - memImg: the pointer to the in‑memory image returned by libraw_dcraw_make_mem_image
 //   - dataSize, height, width, bits: image details
+//   - exifTags: the tags parsed while opening, if CollectExifTags is set
 func (p *Processor) processFile(filepath string) (proc *C.libraw_data_t, memImg *C.libraw_processed_image_t, dataSize C.uint,
-	height, width, bits C.ushort, err error) {
+	height, width, bits C.ushort, exifTags metadata.ExifTags, err error) {
+
+	if p.invalid != nil {
+		err = p.invalid
+		return
+	}
+	if err = p.ctxErr(); err != nil {
+		return
+	}
 
 	proc = C.libraw_init(0)
 	if proc == nil {
@@ -336,19 +357,26 @@ func (p *Processor) processFile(filepath string) (proc *C.libraw_data_t, memImg
 
 	proc.params = p.options.Apply(proc.params)
 	defer p.options.Free(proc.params)
+	proc.rawparams = p.options.Unpack.Apply(proc.rawparams)
+	defer p.options.Unpack.Free(proc.rawparams)
+	watch := p.watchContext(proc)
+	defer watch.stop()
 
 	cFile := C.CString(filepath)
 	defer freeCString(cFile)
 
-	if err = librawErr(C.libraw_open_file(proc, cFile)); err != nil {
+	collector := p.collectExifTags(proc)
+	err = librawErr(C.libraw_open_file(proc, cFile))
+	exifTags = collector.stop()
+	if err != nil {
 		return
 	}
 
-	if err = librawErr(C.libraw_unpack(proc)); err != nil {
+	if err = watch.err(librawErr(C.libraw_unpack(proc))); err != nil {
 		return
 	}
 
-	if err = librawErr(C.libraw_dcraw_process(proc)); err != nil {
+	if err = watch.err(librawErr(C.libraw_dcraw_process(proc))); err != nil {
 		return
 	}
 
@@ -368,7 +396,14 @@ func (p *Processor) processFile(filepath string) (proc *C.libraw_data_t, memImg
 	return
 }
 
+// ConvertToImage converts the interleaved RGB output of LibRaw into an
+// image.Image. 8-bit data yields an *image.RGBA, 16-bit data (as produced
+// with OutputBps = 16, in native byte order) yields an *image.RGBA64.
 func ConvertToImage(data []byte, width, height, bits int) (image.Image, error) {
+	if bits == 16 {
+		return convertToImage16(data, width, height)
+	}
+
 	// Check if we have the expected amount of data for RGB
 	expectedSize := width * height * 3 // 3 bytes per pixel for RGB
 	if len(data) != expectedSize {
@@ -398,8 +433,53 @@ func ConvertToImage(data []byte, width, height, bits int) (image.Image, error) {
 	return img, nil
 }
 
+func convertToImage16(data []byte, width, height int) (image.Image, error) {
+	expectedSize := width * height * 3 * 2 // 3 ushorts per pixel
+	if len(data) != expectedSize {
+		return nil, fmt.Errorf("unexpected data size: got %d, want %d", len(data), expectedSize)
+	}
+
+	img := image.NewRGBA64(image.Rect(0, 0, width, height))
+	for i := range width * height {
+		src := data[i*6:]
+		dst := img.Pix[i*8:]
+		// RGBA64 stores big-endian samples, LibRaw hands out native ushorts
+		for c := range 3 {
+			binary.BigEndian.PutUint16(dst[2*c:], binary.NativeEndian.Uint16(src[2*c:]))
+		}
+		dst[6], dst[7] = 0xff, 0xff
+	}
+
+	return img, nil
+}
+
+// convertToGray handles single channel output, as produced for monochrome
+// sensors and document mode.
+func convertToGray(data []byte, width, height, bits int) (image.Image, error) {
+	expectedSize := width * height * bits / 8
+	if len(data) != expectedSize {
+		return nil, fmt.Errorf("unexpected data size: got %d, want %d", len(data), expectedSize)
+	}
+
+	if bits != 16 {
+		img := image.NewGray(image.Rect(0, 0, width, height))
+		copy(img.Pix, data)
+		return img, nil
+	}
+
+	img := image.NewGray16(image.Rect(0, 0, width, height))
+	for i := range width * height {
+		binary.BigEndian.PutUint16(img.Pix[2*i:], binary.NativeEndian.Uint16(data[2*i:]))
+	}
+	return img, nil
+}
+
 // ExtractThumbnail extracts the embedded thumbnail from the RAW file.
 func (p *Processor) ExtractThumbnail(filepath string) (*Thumbnail, error) {
+	if err := p.ctxErr(); err != nil {
+		return nil, err
+	}
+
 	proc := C.libraw_init(0)
 	if proc == nil {
 		return nil, fmt.Errorf("failed to initialize libraw")
@@ -416,10 +496,17 @@ func (p *Processor) ExtractThumbnail(filepath string) (*Thumbnail, error) {
 		return nil, err
 	}
 
+	if err := p.ctxErr(); err != nil { // opening can take a while on slow storage
+		return nil, err
+	}
 	if err := librawErr(C.libraw_unpack_thumb(proc)); err != nil {
 		return nil, err
 	}
+	return copyThumbnail(proc)
+}
 
+// copyThumbnail copies the unpacked thumbnail of proc into Go memory.
+func copyThumbnail(proc *C.libraw_data_t) (*Thumbnail, error) {
 	var errc C.int
 	memThumb := C.libraw_dcraw_make_mem_thumb(proc, &errc)
 	if memThumb == nil {
@@ -454,14 +541,18 @@ func (p *Processor) ExtractThumbnail(filepath string) (*Thumbnail, error) {
 	return &Thumbnail{
 		Format: format,
 		Data:   finalData,
+		Width:  uint16(memThumb.width),
+		Height: uint16(memThumb.height),
 		Colors: uint16(memThumb.colors),
 		Bits:   uint16(memThumb.bits),
 	}, nil
 }
 
 // ProcessRaw processes a RAW file and returns an image.Image along with metadata.
+// The image is an *image.RGBA, or an *image.RGBA64 when OutputBps is 16.
+// Single channel output (monochrome sensors) yields *image.Gray or *image.Gray16.
 func (p *Processor) ProcessRaw(filepath string) (image.Image, metadata.ImgMetadata, error) {
-	proc, dataPtr, dataSize, height, width, bits, err := p.processFile(filepath)
+	proc, dataPtr, dataSize, height, width, bits, exifTags, err := p.processFile(filepath)
 	if err != nil {
 		return nil, metadata.ImgMetadata{}, err
 	}
@@ -470,60 +561,17 @@ func (p *Processor) ProcessRaw(filepath string) (image.Image, metadata.ImgMetada
 	// Convert raw bytes to Go slice
 	dataBytes := C.GoBytes(unsafe.Pointer(&dataPtr.data[0]), C.int(dataSize))
 
-	// Handle different bit depths
-	if bits > 8 {
-		// Convert higher bit depth to 8-bit
-		adjustedData := make([]byte, width*height*3)
-		for i := 0; i < len(dataBytes); i += 2 {
-			// Combine two bytes into one, shifting to 8-bit depth
-			if i+1 < len(dataBytes) {
-				value := (uint16(dataBytes[i]) << 8) | uint16(dataBytes[i+1])
-				adjustedData[i/2] = byte(value >> (bits - 8))
-			}
-		}
-		dataBytes = adjustedData
+	var img image.Image
+	if dataPtr.colors == 1 {
+		img, err = convertToGray(dataBytes, int(width), int(height), int(bits))
+	} else {
+		img, err = ConvertToImage(dataBytes, int(width), int(height), int(bits))
 	}
-
-	img, err := ConvertToImage(dataBytes, int(width), int(height), 8)
 	if err != nil {
 		return nil, metadata.ImgMetadata{}, fmt.Errorf("convert to image: %v", err)
 	}
 
-	other := C.libraw_get_imgother(proc)
-	timestamp := int64(other.timestamp)
-	captureTime := time.Unix(timestamp, 0)
-
-	var isFoveon bool = false
-	if uint(proc.idata.is_foveon) == 1 {
-		isFoveon = true
-	}
-
-	idata := metadata.LibRawIData{
-		Make:             cArrayToString(proc.idata.make),
-		Model:            cArrayToString(proc.idata.model),
-		MakerIndex:       uint(proc.idata.maker_index),
-		Software:         cArrayToString(proc.idata.software),
-		RawCount:         uint(proc.idata.raw_count),
-		IsFoveon:         isFoveon,
-		DngVersion:       uint(proc.idata.dng_version),
-		Colors:           int(proc.idata.colors),
-		ColorDescription: cColorDescToRunes(proc.idata.cdesc),
-	}
-
-	sizes := metadata.LibRawSizes{
-		RawHeight: uint16(proc.sizes.raw_height),
-		RawWidth:  uint16(proc.sizes.raw_width),
-		Height:    uint16(proc.sizes.height),
-		Width:     uint16(proc.sizes.width),
-		Iheight:   uint16(proc.sizes.iheight),
-		Iwidth:    uint16(proc.sizes.iwidth),
-	}
-
-	meta := metadata.ImgMetadata{
-		CaptureTimestamp: timestamp,
-		CaptureDate:      captureTime,
-		IData:            idata,
-		Sizes:            sizes,
-	}
+	meta := readMetadata(proc)
+	meta.ExifTags = exifTags
 	return img, meta, nil
 }
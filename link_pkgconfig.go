@@ -0,0 +1,8 @@
+//go:build !libraw_static && !libraw_nopkgconfig && !libraw_nothread
+
+package golibraw
+
+// Default: the thread-safe libraw_r found through pkg-config.
+
+// #cgo pkg-config: libraw_r
+import "C"
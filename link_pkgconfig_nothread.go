@@ -0,0 +1,8 @@
+//go:build !libraw_static && !libraw_nopkgconfig && libraw_nothread
+
+package golibraw
+
+// The non thread-safe libraw found through pkg-config.
+
+// #cgo pkg-config: libraw
+import "C"
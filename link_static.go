@@ -0,0 +1,15 @@
+//go:build libraw_static
+
+package golibraw
+
+// The static library in libraw_dist, as built by install_libraw_windows.ps1.
+// Libraries LibRaw itself was built against (zlib, libjpeg, lcms2, OpenMP)
+// must be added through CGO_LDFLAGS.
+
+// #cgo CFLAGS: -I${SRCDIR}/libraw_dist/include
+// #cgo CXXFLAGS: -I${SRCDIR}/libraw_dist/include
+// #cgo LDFLAGS: ${SRCDIR}/libraw_dist/lib/libraw.a
+// #cgo linux LDFLAGS: -lstdc++ -lm
+// #cgo darwin LDFLAGS: -lc++
+// #cgo windows LDFLAGS: -lws2_32 -lstdc++
+import "C"
@@ -0,0 +1,13 @@
+//go:build !libraw_static && libraw_nopkgconfig
+
+package golibraw
+
+// The system LibRaw on the default search paths, for hosts without
+// pkg-config files. Extra paths can be given in CGO_CFLAGS/CGO_LDFLAGS.
+
+// #cgo !libraw_nothread LDFLAGS: -lraw_r
+// #cgo libraw_nothread LDFLAGS: -lraw
+// #cgo linux LDFLAGS: -lstdc++ -lm
+// #cgo darwin LDFLAGS: -lc++
+// #cgo windows LDFLAGS: -lws2_32 -lstdc++
+import "C"
@@ -0,0 +1,195 @@
+package golibraw
+
+// #include "libraw/libraw.h"
+import "C"
+
+import (
+	"strings"
+
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// readMakernotes converts the makernotes of the vendor that made the
+// camera. LibRaw normalizes the make, e.g. "NIKON CORPORATION" to "Nikon".
+func readMakernotes(proc *C.libraw_data_t) metadata.Makernotes {
+	mn := &proc.makernotes
+	var notes metadata.Makernotes
+
+	maker := strings.ToLower(cArrayToString(proc.idata.make))
+	switch {
+	case maker == "canon":
+		notes.Canon = readCanon(&mn.canon)
+	case maker == "nikon":
+		notes.Nikon = readNikon(&mn.nikon)
+	case maker == "sony":
+		notes.Sony = readSony(&mn.sony)
+	case maker == "fujifilm":
+		notes.Fuji = readFuji(&mn.fuji)
+	case maker == "olympus" || strings.HasPrefix(maker, "om digital"):
+		notes.Olympus = readOlympus(&mn.olympus)
+	case maker == "panasonic" || maker == "leica":
+		notes.Panasonic = readPanasonic(&mn.panasonic)
+	case maker == "pentax" || maker == "ricoh":
+		notes.Pentax = readPentax(&mn.pentax)
+	}
+	return notes
+}
+
+func readCanon(c *C.libraw_canon_makernotes_t) *metadata.CanonMakernotes {
+	return &metadata.CanonMakernotes{
+		ColorDataVer:          int(c.ColorDataVer),
+		ColorDataSubVer:       int(c.ColorDataSubVer),
+		SensorWidth:           int(c.SensorWidth),
+		SensorHeight:          int(c.SensorHeight),
+		SensorLeftBorder:      int(c.SensorLeftBorder),
+		SensorTopBorder:       int(c.SensorTopBorder),
+		SensorRightBorder:     int(c.SensorRightBorder),
+		SensorBottomBorder:    int(c.SensorBottomBorder),
+		AverageBlackLevel:     int(c.AverageBlackLevel),
+		ContinuousDrive:       int(c.ContinuousDrive),
+		MeteringMode:          int(c.MeteringMode),
+		ExposureMode:          int(c.ExposureMode),
+		ImageStabilization:    int(c.ImageStabilization),
+		RecordMode:            int(c.RecordMode),
+		SRAWQuality:           int(c.SRAWQuality),
+		Quality:               int(c.Quality),
+		CanonLog:              int(c.CanonLog),
+		HighlightTonePriority: int(c.HighlightTonePriority),
+		AutoLightingOptimizer: int(c.AutoLightingOptimizer),
+		AFMicroAdjMode:        int(c.AFMicroAdjMode),
+		AFMicroAdjValue:       float32(c.AFMicroAdjValue),
+		RFLensID:              int(c.RF_lensID),
+	}
+}
+
+func readNikon(n *C.libraw_nikon_makernotes_t) *metadata.NikonMakernotes {
+	notes := &metadata.NikonMakernotes{
+		NEFCompression:     int(n.NEFCompression),
+		ExposureMode:       int(n.ExposureMode),
+		ExposureProgram:    int(n.ExposureProgram),
+		ShootingMode:       uint16(n.ShootingMode),
+		ActiveDLighting:    uint16(n.ActiveDLighting),
+		VibrationReduction: uint8(n.VibrationReduction),
+		VRMode:             uint8(n.VRMode),
+		PictureControlName: cCharsToString(n.PictureControlName[:]),
+		PictureControlBase: cCharsToString(n.PictureControlBase[:]),
+		SensorWidth:        uint16(n.SensorWidth),
+		SensorHeight:       uint16(n.SensorHeight),
+		AFFineTune:         uint8(n.AFFineTune),
+		AFFineTuneAdj:      int8(n.AFFineTuneAdj),
+		RollAngle:          float64(n.RollAngle),
+		PitchAngle:         float64(n.PitchAngle),
+		YawAngle:           float64(n.YawAngle),
+	}
+	for i := range notes.NEFBitDepth {
+		notes.NEFBitDepth[i] = uint16(n.NEFBitDepth[i])
+	}
+	return notes
+}
+
+func readSony(s *C.libraw_sony_info_t) *metadata.SonyMakernotes {
+	return &metadata.SonyMakernotes{
+		CameraType:                    uint16(s.CameraType),
+		Firmware:                      float32(s.firmware),
+		SonyRawFileType:               uint16(s.SonyRawFileType),
+		RAWFileType:                   uint16(s.RAWFileType),
+		Quality:                       uint(s.Quality),
+		FileFormat:                    uint16(s.FileFormat),
+		MetaVersion:                   cCharsToString(s.MetaVersion[:]),
+		AFAreaMode:                    uint16(s.AFAreaMode),
+		AFPointSelected:               uint8(s.AFPointSelected),
+		AFTracking:                    uint8(s.AFTracking),
+		FocusPosition:                 uint16(s.FocusPosition),
+		AFMicroAdjOn:                  s.AFMicroAdjOn > 0,
+		AFMicroAdjValue:               int8(s.AFMicroAdjValue),
+		LongExposureNoiseReduction:    uint(s.LongExposureNoiseReduction),
+		HighISONoiseReduction:         uint16(s.HighISONoiseReduction),
+		HDR:                           [2]uint16{uint16(s.HDR[0]), uint16(s.HDR[1])},
+		ElectronicFrontCurtainShutter: uint(s.ElectronicFrontCurtainShutter),
+		ShotNumberSincePowerUp:        uint(s.ShotNumberSincePowerUp),
+		PixelShiftGroupID:             uint(s.PixelShiftGroupID),
+		PixelShiftShots:               int(s.nShotsInPixelShiftGroup),
+		PixelShiftNumberInGroup:       int(s.numInPixelShiftGroup),
+	}
+}
+
+func readFuji(f *C.libraw_fuji_info_t) *metadata.FujiMakernotes {
+	return &metadata.FujiMakernotes{
+		FilmMode:                uint16(f.FilmMode),
+		DynamicRange:            uint16(f.DynamicRange),
+		DynamicRangeSetting:     uint16(f.DynamicRangeSetting),
+		DevelopmentDynamicRange: uint16(f.DevelopmentDynamicRange),
+		AutoDynamicRange:        uint16(f.AutoDynamicRange),
+		DRangePriority:          uint16(f.DRangePriority),
+		DRangePriorityAuto:      uint16(f.DRangePriorityAuto),
+		DRangePriorityFixed:     uint16(f.DRangePriorityFixed),
+		FujiModel:               cCharsToString(f.FujiModel[:]),
+		SensorID:                cCharsToString(f.SensorID[:]),
+		RAFVersion:              cCharsToString(f.RAFVersion[:]),
+		FocusMode:               uint16(f.FocusMode),
+		AFMode:                  uint16(f.AFMode),
+		ShutterType:             uint16(f.ShutterType),
+		ExrMode:                 uint16(f.ExrMode),
+		CropMode:                uint16(f.CropMode),
+		DriveMode:               int(f.DriveMode),
+		Rating:                  uint(f.Rating),
+		ImageCount:              int(f.ImageCount),
+		PixelShiftOffset:        [2]float32{float32(f.PixelShiftOffset[0]), float32(f.PixelShiftOffset[1])},
+	}
+}
+
+func readOlympus(o *C.libraw_olympus_makernotes_t) *metadata.OlympusMakernotes {
+	notes := &metadata.OlympusMakernotes{
+		CameraType2:   cCharsToString(o.CameraType2[:]),
+		ValidBits:     uint16(o.ValidBits),
+		ColorSpace:    uint16(o.ColorSpace),
+		FocusMode:     [2]uint16{uint16(o.FocusMode[0]), uint16(o.FocusMode[1])},
+		AutoFocus:     uint16(o.AutoFocus),
+		AFPoint:       uint16(o.AFPoint),
+		AFResult:      uint16(o.AFResult),
+		FocusDistance: float64(o.FocusDistance),
+		StackedImage:  [2]uint{uint(o.StackedImage[0]), uint(o.StackedImage[1])},
+		IsLiveND:      o.isLiveND != 0,
+		LiveNDFactor:  uint(o.LiveNDfactor),
+	}
+	for i := range notes.DriveMode {
+		notes.DriveMode[i] = int(o.DriveMode[i])
+	}
+	for i := range notes.SpecialMode {
+		notes.SpecialMode[i] = uint(o.SpecialMode[i])
+	}
+	return notes
+}
+
+func readPanasonic(p *C.libraw_panasonic_makernotes_t) *metadata.PanasonicMakernotes {
+	notes := &metadata.PanasonicMakernotes{
+		Compression:   uint16(p.Compression),
+		BlackLevelDim: uint16(p.BlackLevelDim),
+		Multishot:     uint(p.Multishot),
+		Gamma:         float32(p.gamma),
+	}
+	for i := range notes.BlackLevel {
+		notes.BlackLevel[i] = float32(p.BlackLevel[i])
+	}
+	for i := range notes.HighISOMultiplier {
+		notes.HighISOMultiplier[i] = int(p.HighISOMultiplier[i])
+	}
+	return notes
+}
+
+func readPentax(p *C.libraw_pentax_makernotes_t) *metadata.PentaxMakernotes {
+	notes := &metadata.PentaxMakernotes{
+		FocusMode:       [2]uint16{uint16(p.FocusMode[0]), uint16(p.FocusMode[1])},
+		AFPointSelected: [2]uint16{uint16(p.AFPointSelected[0]), uint16(p.AFPointSelected[1])},
+		AFPointsInFocus: uint(p.AFPointsInFocus),
+		FocusPosition:   uint16(p.FocusPosition),
+		AFAdjustment:    int16(p.AFAdjustment),
+		AFPointMode:     uint8(p.AFPointMode),
+		MultiExposure:   uint8(p.MultiExposure),
+		Quality:         uint16(p.Quality),
+	}
+	for i := range notes.DriveMode {
+		notes.DriveMode[i] = uint8(p.DriveMode[i])
+	}
+	return notes
+}
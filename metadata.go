@@ -0,0 +1,176 @@
+package golibraw
+
+// #include "libraw/libraw.h"
+import "C"
+
+import (
+	"time"
+
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// readMetadata collects the metadata of an opened file.
+func readMetadata(proc *C.libraw_data_t) metadata.ImgMetadata {
+	other := C.libraw_get_imgother(proc)
+	timestamp := int64(other.timestamp)
+	captureTime := time.Unix(timestamp, 0)
+
+	var isFoveon bool = false
+	if uint(proc.idata.is_foveon) == 1 {
+		isFoveon = true
+	}
+
+	idata := metadata.LibRawIData{
+		Make:             cArrayToString(proc.idata.make),
+		Model:            cArrayToString(proc.idata.model),
+		MakerIndex:       uint(proc.idata.maker_index),
+		Software:         cArrayToString(proc.idata.software),
+		RawCount:         uint(proc.idata.raw_count),
+		IsFoveon:         isFoveon,
+		DngVersion:       uint(proc.idata.dng_version),
+		Colors:           int(proc.idata.colors),
+		ColorDescription: cColorDescToRunes(proc.idata.cdesc),
+	}
+
+	sizes := metadata.LibRawSizes{
+		RawHeight: uint16(proc.sizes.raw_height),
+		RawWidth:  uint16(proc.sizes.raw_width),
+		Height:    uint16(proc.sizes.height),
+		Width:     uint16(proc.sizes.width),
+		Iheight:   uint16(proc.sizes.iheight),
+		Iwidth:    uint16(proc.sizes.iwidth),
+
+		TopMargin:  uint16(proc.sizes.top_margin),
+		LeftMargin: uint16(proc.sizes.left_margin),
+		Flip:       int(proc.sizes.flip),
+	}
+
+	return metadata.ImgMetadata{
+		CaptureTimestamp: timestamp,
+		CaptureDate:      captureTime,
+		IData:            idata,
+		Sizes:            sizes,
+		Color:            readColorData(&proc.rawdata.color),
+		Other:            readOther(other),
+		Lens:             readLens(C.libraw_get_lensinfo(proc)),
+		ShootingInfo:     readShootingInfo(&proc.shootinginfo),
+		Makernotes:       readMakernotes(proc),
+	}
+}
+
+func readShootingInfo(info *C.libraw_shootinginfo_t) metadata.ShootingInfo {
+	return metadata.ShootingInfo{
+		DriveMode:          int(info.DriveMode),
+		FocusMode:          int(info.FocusMode),
+		MeteringMode:       int(info.MeteringMode),
+		AFPoint:            int(info.AFPoint),
+		ExposureMode:       int(info.ExposureMode),
+		ExposureProgram:    int(info.ExposureProgram),
+		ImageStabilization: int(info.ImageStabilization),
+		BodySerial:         cCharsToString(info.BodySerial[:]),
+		InternalBodySerial: cCharsToString(info.InternalBodySerial[:]),
+	}
+}
+
+func readOther(other *C.libraw_imgother_t) metadata.ImgOther {
+	gps := &other.parsed_gps
+	info := metadata.ImgOther{
+		ISOSpeed:    float32(other.iso_speed),
+		Shutter:     float32(other.shutter),
+		Aperture:    float32(other.aperture),
+		FocalLength: float32(other.focal_len),
+		ShotOrder:   uint(other.shot_order),
+		Description: cCharsToString(other.desc[:]),
+		Artist:      cCharsToString(other.artist[:]),
+		GPS: metadata.GPSInfo{
+			Altitude:     float32(gps.altitude),
+			AltitudeRef:  uint8(gps.altref),
+			LatitudeRef:  byte(gps.latref),
+			LongitudeRef: byte(gps.longref),
+			Status:       byte(gps.gpsstatus),
+			Parsed:       gps.gpsparsed != 0,
+		},
+	}
+	for i := range 3 {
+		info.GPS.Latitude[i] = float32(gps.latitude[i])
+		info.GPS.Longitude[i] = float32(gps.longitude[i])
+		info.GPS.Timestamp[i] = float32(gps.gpstimestamp[i])
+	}
+	return info
+}
+
+// readLens prefers the EXIF lens fields and falls back to what LibRaw
+// decoded from the makernotes.
+func readLens(lens *C.libraw_lensinfo_t) metadata.LensInfo {
+	info := metadata.LensInfo{
+		Make:              cCharsToString(lens.LensMake[:]),
+		Model:             cCharsToString(lens.Lens[:]),
+		Serial:            cCharsToString(lens.LensSerial[:]),
+		MinFocal:          float32(lens.MinFocal),
+		MaxFocal:          float32(lens.MaxFocal),
+		MaxAp4MinFocal:    float32(lens.MaxAp4MinFocal),
+		MaxAp4MaxFocal:    float32(lens.MaxAp4MaxFocal),
+		FocalLengthIn35mm: uint16(lens.FocalLengthIn35mmFormat),
+	}
+
+	mn := &lens.makernotes
+	if info.Model == "" {
+		info.Model = cCharsToString(mn.Lens[:])
+	}
+	if info.MinFocal == 0 && info.MaxFocal == 0 {
+		info.MinFocal, info.MaxFocal = float32(mn.MinFocal), float32(mn.MaxFocal)
+		info.MaxAp4MinFocal, info.MaxAp4MaxFocal = float32(mn.MaxAp4MinFocal), float32(mn.MaxAp4MaxFocal)
+	}
+	if info.FocalLengthIn35mm == 0 {
+		info.FocalLengthIn35mm = uint16(mn.FocalLengthIn35mmFormat + 0.5)
+	}
+	return info
+}
+
+// cCharsToString converts a NUL terminated C char array of any size.
+func cCharsToString(s []C.char) string {
+	b := make([]byte, 0, len(s))
+	for _, c := range s {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return string(b)
+}
+
+// readColorData converts the color data saved by libraw_unpack; the live
+// copy in proc.color is modified by processing.
+func readColorData(color *C.libraw_colordata_t) metadata.ColorData {
+	cd := metadata.ColorData{
+		Black:       uint32(color.black),
+		Maximum:     uint32(color.maximum),
+		DataMaximum: uint32(color.data_maximum),
+		FlashUsed:   float32(color.flash_used),
+		RawBps:      uint(color.raw_bps),
+	}
+
+	for c := range 4 {
+		cd.ChannelBlack[c] = uint32(color.cblack[c])
+		cd.CamMul[c] = float32(color.cam_mul[c])
+		cd.PreMul[c] = float32(color.pre_mul[c])
+		for j := range 3 {
+			cd.CamXYZ[c][j] = float32(color.cam_xyz[c][j])
+			cd.RGBCam[j][c] = float32(color.rgb_cam[j][c])
+		}
+	}
+
+	// cblack[4] x cblack[5] pattern stored from cblack[6] onwards
+	rows, cols := int(color.cblack[4]), int(color.cblack[5])
+	if rows > 0 && cols > 0 && 6+rows*cols <= len(color.cblack) {
+		cd.BlackPattern = make([][]uint32, rows)
+		for r := range rows {
+			cd.BlackPattern[r] = make([]uint32, cols)
+			for c := range cols {
+				cd.BlackPattern[r][c] = uint32(color.cblack[6+r*cols+c])
+			}
+		}
+	}
+
+	return cd
+}
@@ -0,0 +1,234 @@
+package golibraw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+
+	"github.com/stmtc233/go-libraw/pkg/icc"
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+	"github.com/stmtc233/go-libraw/pkg/pixelshift"
+)
+
+// PixelShiftFrame converts the visible area of a Bayer frame into the
+// input of pixelshift.Composite: black subtracted and scaled so that the
+// white level is 1.
+func (r *RawImage) PixelShiftFrame(meta *metadata.ImgMetadata) (pixelshift.Frame, error) {
+	if r.Filters < 1000 {
+		return pixelshift.Frame{}, fmt.Errorf("pixel shift needs a Bayer sensor")
+	}
+	cdesc := meta.IData.ColorDescription
+	channel := func(color int) (int, error) {
+		if color >= 0 && color < len(cdesc) {
+			switch cdesc[color] {
+			case 'R':
+				return 0, nil
+			case 'G':
+				return 1, nil
+			case 'B':
+				return 2, nil
+			}
+		}
+		return 0, fmt.Errorf("unsupported color filter array %q", string(cdesc[:]))
+	}
+
+	v := r.Visible
+	f := pixelshift.Frame{Width: v.Dx(), Height: v.Dy(), Pix: make([]float32, v.Dx()*v.Dy())}
+	for y := range 2 {
+		for x := range 2 {
+			c, err := channel(r.CFAColor(y, x))
+			if err != nil {
+				return pixelshift.Frame{}, err
+			}
+			f.Pattern[y][x] = c
+		}
+	}
+
+	color := &meta.Color
+	scale := 1 / float32(max(color.Maximum-color.Black, 1))
+	for y := range f.Height {
+		src := r.Pix[(v.Min.Y+y)*r.Width+v.Min.X:]
+		for x := range f.Width {
+			black := color.BlackAt(y, x, r.CFAColor(y, x))
+			f.Pix[y*f.Width+x] = max(float32(src[x])-float32(black), 0) * scale
+		}
+	}
+	return f, nil
+}
+
+// ProcessPixelShift combines the sub-frames of a pixel shift capture
+// stored in one file, as Pentax cameras write them (see
+// pixelshift.Composite), and renders them without demosaicing. Captures
+// written as one file per frame, as by Sony, go to ProcessPixelShiftFiles.
+// Olympus/OM System and Panasonic combine the frames in the camera, so
+// their high resolution files decode like any other. Only part of the
+// LibRaw pipeline applies to the result:
+//
+//   - white balance from UserMul, the as shot multipliers with UseCameraWb,
+//     or the daylight multipliers;
+//   - OutputColor (Raw keeps camera RGB) and the Gamm curve;
+//   - Bright and OutputBps.
+//
+// Highlights are clipped and the image is not rotated. The metadata is
+// that of the reference frame.
+func (p *Processor) ProcessPixelShift(filepath string, ps pixelshift.Options) (image.Image, metadata.ImgMetadata, error) {
+	// Pentax files only report their sub-frames when asked to
+	opts := p.options
+	if opts.Unpack.IsZero() {
+		opts.Unpack = DefaultUnpackOptions()
+	}
+	opts.Unpack = opts.Unpack.clone()
+	opts.Unpack.Options |= RawPentaxPSAllFrames
+
+	var frames []pixelshift.Frame
+	var meta metadata.ImgMetadata
+	for frame, err := range p.derive(opts).UnpackFrames(filepath) {
+		if err != nil {
+			return nil, metadata.ImgMetadata{}, err
+		}
+		f, err := frame.Raw.PixelShiftFrame(&frame.Metadata)
+		if err != nil {
+			return nil, metadata.ImgMetadata{}, err
+		}
+		frames = append(frames, f)
+		if frame.Index == ps.Reference {
+			meta = frame.Metadata
+		}
+	}
+	if len(frames) < 2 {
+		return nil, metadata.ImgMetadata{}, fmt.Errorf("%s has %d frame, not a pixel shift capture", filepath, len(frames))
+	}
+	return p.compositePixelShift(frames, meta, ps)
+}
+
+// ProcessPixelShiftFiles is ProcessPixelShift for captures written as one
+// file per frame, such as the 4 or 16 ARW files of Sony's pixel shift
+// multi shooting. The files are the frames in shooting order, and
+// ps.Reference indexes them. Sony's shift sequence may differ from
+// pixelshift.DefaultOffsets; set ps.Offsets when it does.
+func (p *Processor) ProcessPixelShiftFiles(paths []string, ps pixelshift.Options) (image.Image, metadata.ImgMetadata, error) {
+	if len(paths) < 2 {
+		return nil, metadata.ImgMetadata{}, fmt.Errorf("pixel shift needs at least 2 files, got %d", len(paths))
+	}
+	if ps.Reference < 0 || ps.Reference >= len(paths) {
+		return nil, metadata.ImgMetadata{}, fmt.Errorf("reference frame %d out of range, have %d files", ps.Reference, len(paths))
+	}
+
+	frames := make([]pixelshift.Frame, len(paths))
+	var meta metadata.ImgMetadata
+	for i, path := range paths {
+		raw, m, err := p.UnpackRaw(path)
+		if err != nil {
+			return nil, metadata.ImgMetadata{}, fmt.Errorf("%s: %w", path, err)
+		}
+		if frames[i], err = raw.PixelShiftFrame(&m); err != nil {
+			return nil, metadata.ImgMetadata{}, fmt.Errorf("%s: %w", path, err)
+		}
+		if i == ps.Reference {
+			meta = m
+		}
+	}
+	return p.compositePixelShift(frames, meta, ps)
+}
+
+// compositePixelShift combines frames and renders them with the color
+// data of the reference frame's metadata.
+func (p *Processor) compositePixelShift(frames []pixelshift.Frame, meta metadata.ImgMetadata, ps pixelshift.Options) (image.Image, metadata.ImgMetadata, error) {
+	composite, err := pixelshift.Composite(frames, ps)
+	if err != nil {
+		return nil, metadata.ImgMetadata{}, err
+	}
+	return p.options.renderCameraRGB(composite, &meta.Color), meta, nil
+}
+
+// renderCameraRGB converts full color camera data to the output color
+// space and encoding of the options.
+func (opts *ProcessorOptions) renderCameraRGB(src *pixelshift.Image, color *metadata.ColorData) image.Image {
+	mul := color.PreMul
+	switch {
+	case opts.UserMul[0] > 0:
+		mul = opts.UserMul
+	case opts.UseCameraWb && color.CamMul[0] > 0:
+		mul = color.CamMul
+	}
+	var wb [3]float64
+	for c := range 3 {
+		wb[c] = float64(mul[c]) / float64(max(mul[1], 1e-6)) * float64(opts.Bright)
+	}
+
+	// camera RGB -> sRGB -> output space, with white balance folded in
+	m := [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	if cs, ok := opts.OutputColor.ColorSpace(); ok {
+		var rgbCam [3][3]float64
+		for i := range 3 {
+			for j := range 3 {
+				rgbCam[i][j] = float64(color.RGBCam[i][j])
+			}
+		}
+		m = mul3(icc.SRGBSpace.ConvertTo(cs), rgbCam)
+	}
+	for i := range 3 {
+		for j := range 3 {
+			m[i][j] *= wb[j]
+		}
+	}
+
+	curve := icc.NewToneCurve(opts.Gamm[0], opts.Gamm[1])
+	// the curve is tabulated at the output precision, so 16-bit output
+	// keeps every level of the composite; for 8 bits 4096 linear steps
+	// are enough to leave no gaps in the shadows
+	lut := make([]uint16, 4096)
+	if opts.OutputBps == 16 {
+		lut = make([]uint16, 65536)
+	}
+	for i := range lut {
+		lut[i] = uint16(curve.Encode(float64(i)/float64(len(lut)-1))*0xffff + 0.5)
+	}
+	encode := func(v float64) uint16 {
+		return lut[int(min(max(v, 0), 1)*float64(len(lut)-1)+0.5)]
+	}
+
+	rect := image.Rect(0, 0, src.Width, src.Height)
+	var rgba *image.RGBA
+	var rgba64 *image.RGBA64
+	if opts.OutputBps == 16 {
+		rgba64 = image.NewRGBA64(rect)
+	} else {
+		rgba = image.NewRGBA(rect)
+	}
+	for y := range src.Height {
+		for x := range src.Width {
+			cam := src.RGB(x, y)
+			i := y*src.Width + x
+			for c := range 3 {
+				v := encode(m[c][0]*float64(cam[0]) + m[c][1]*float64(cam[1]) + m[c][2]*float64(cam[2]))
+				if rgba64 != nil {
+					binary.BigEndian.PutUint16(rgba64.Pix[8*i+2*c:], v)
+				} else {
+					rgba.Pix[4*i+c] = uint8(v >> 8)
+				}
+			}
+			if rgba64 != nil {
+				rgba64.Pix[8*i+6], rgba64.Pix[8*i+7] = 0xff, 0xff
+			} else {
+				rgba.Pix[4*i+3] = 0xff
+			}
+		}
+	}
+	if rgba64 != nil {
+		return rgba64
+	}
+	return rgba
+}
+
+func mul3(a, b [3][3]float64) [3][3]float64 {
+	var out [3][3]float64
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				out[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return out
+}
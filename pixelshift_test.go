@@ -0,0 +1,83 @@
+package golibraw
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+	"github.com/stmtc233/go-libraw/pkg/pixelshift"
+)
+
+func TestPixelShiftFrame(t *testing.T) {
+	raw := &RawImage{
+		Width: 6, Height: 4, Pix: make([]uint16, 24),
+		Visible: image.Rect(2, 0, 6, 4),
+		Filters: 0x94949494, // RGGB
+	}
+	for i := range raw.Pix {
+		raw.Pix[i] = 1000
+	}
+	meta := &metadata.ImgMetadata{
+		IData: metadata.LibRawIData{ColorDescription: [5]rune{'R', 'G', 'B', 'G'}},
+		Color: metadata.ColorData{Black: 200, ChannelBlack: [4]uint32{0, 0, 400, 0}, Maximum: 1800},
+	}
+
+	f, err := raw.PixelShiftFrame(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Width != 4 || f.Height != 4 || f.Pattern != [2][2]int{{0, 1}, {1, 2}} {
+		t.Fatalf("frame %dx%d with pattern %v", f.Width, f.Height, f.Pattern)
+	}
+	if f.Pix[0] != 0.5 || f.Pix[5] != 0.25 {
+		t.Errorf("red = %v, blue = %v, want 0.5 and 0.25", f.Pix[0], f.Pix[5])
+	}
+}
+
+func TestRenderCameraRGB(t *testing.T) {
+	src := &pixelshift.Image{Width: 1, Height: 1, Pix: []float32{0.25, 0.5, 0.125}}
+	color := &metadata.ColorData{PreMul: [4]float32{2, 1, 4, 1}}
+
+	opts := NewProcessorOptions()
+	opts.OutputColor = Raw
+	opts.Gamm = [6]float64{1, 1}
+	opts.OutputBps = 16
+
+	img := opts.renderCameraRGB(src, color).(*image.RGBA64)
+	if c := img.RGBA64At(0, 0); c.R != c.G || c.G != c.B || c.G < 0x7ff0 || c.G > 0x8010 {
+		t.Errorf("white balanced gray = %v", c)
+	}
+	// 16-bit output keeps levels a 12-bit table would merge
+	src = &pixelshift.Image{Width: 1, Height: 1, Pix: []float32{1234.0 / 0xffff, 1235.0 / 0xffff, 1236.0 / 0xffff}}
+	img = opts.renderCameraRGB(src, &metadata.ColorData{PreMul: [4]float32{1, 1, 1, 1}}).(*image.RGBA64)
+	if c := img.RGBA64At(0, 0); c.R != 1234 || c.G != 1235 || c.B != 1236 {
+		t.Errorf("linear levels = %d %d %d, want 1234 1235 1236", c.R, c.G, c.B)
+	}
+}
+
+func TestProcessPixelShift(t *testing.T) {
+	p := NewProcessor(NewProcessorOptions())
+	for _, path := range getAllFilesInTestDir() {
+		n, err := p.FrameCount(path)
+		if err != nil || (n != 4 && n != 16) {
+			continue
+		}
+		img, _, err := p.ProcessPixelShift(path, pixelshift.Options{MotionThreshold: 0.1})
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		t.Logf("%s: %v", path, img.Bounds())
+	}
+}
+
+func TestProcessPixelShiftFilesArguments(t *testing.T) {
+	p := NewProcessor(NewProcessorOptions())
+	if _, _, err := p.ProcessPixelShiftFiles([]string{"a.ARW"}, pixelshift.Options{}); err == nil {
+		t.Error("single file accepted")
+	}
+	paths := []string{"a.ARW", "b.ARW", "c.ARW", "d.ARW"}
+	if _, _, err := p.ProcessPixelShiftFiles(paths, pixelshift.Options{Reference: 4}); err == nil {
+		t.Error("reference frame out of range accepted")
+	}
+}
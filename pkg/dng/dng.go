@@ -0,0 +1,277 @@
+// Package dng writes Adobe Digital Negative files from unpacked CFA data.
+package dng
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/stmtc233/go-libraw/pkg/internal/exif"
+	"github.com/stmtc233/go-libraw/pkg/internal/tiffio"
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// Compression selects how the raw data is stored.
+type Compression int
+
+const (
+	Uncompressed Compression = iota
+	LosslessJPEG             // DNG compression 7, 256x256 tiles
+)
+
+// Plane colors used in CFA patterns.
+const (
+	Red   uint8 = 0
+	Green uint8 = 1
+	Blue  uint8 = 2
+)
+
+// Image is the raw data to store, cropped to the visible area.
+type Image struct {
+	Width  int
+	Height int
+	Pix    []uint16 // Width*Height samples
+
+	// CFA is the color filter pattern at the top-left of Pix, as plane
+	// colors (Red, Green, Blue). Its dimensions are the repeat pattern,
+	// e.g. 2x2 for Bayer and 6x6 for X-Trans.
+	CFA [][]uint8
+
+	// BlackLevel holds the black level for each position of a repeating
+	// pattern anchored at the top-left of Pix; a 1x1 pattern applies to
+	// every pixel.
+	BlackLevel [][]uint32
+	WhiteLevel uint32
+
+	// ColorMatrix converts XYZ (D65) to camera space, one row per plane.
+	ColorMatrix [3][3]float64
+	// AsShotNeutral is the camera neutral of the as shot white balance;
+	// zero values are omitted from the file.
+	AsShotNeutral [3]float64
+	// Orientation is the EXIF orientation of the sensor data.
+	Orientation int
+}
+
+// Preview is an embedded, already rendered preview image.
+type Preview struct {
+	Width  int
+	Height int
+	// Data is a JPEG stream when JPEG is set, interleaved 8-bit RGB
+	// samples otherwise.
+	Data []byte
+	JPEG bool
+}
+
+// Options configures Encode.
+type Options struct {
+	Compression Compression
+	Preview     *Preview
+	// Metadata fills Make, Model, UniqueCameraModel and the EXIF tags.
+	Metadata *metadata.ImgMetadata
+}
+
+const (
+	tagNewSubfileType            = 254
+	tagImageWidth                = 256
+	tagImageLength               = 257
+	tagBitsPerSample             = 258
+	tagCompression               = 259
+	tagPhotometricInterpretation = 262
+	tagSamplesPerPixel           = 277
+	tagRowsPerStrip              = 278
+	tagPlanarConfiguration       = 284
+	tagTileWidth                 = 322
+	tagTileLength                = 323
+	tagCFARepeatPatternDim       = 33421
+	tagCFAPattern                = 33422
+	tagDNGVersion                = 50706
+	tagDNGBackwardVersion        = 50707
+	tagUniqueCameraModel         = 50708
+	tagCFAPlaneColor             = 50710
+	tagCFALayout                 = 50711
+	tagBlackLevelRepeatDim       = 50713
+	tagBlackLevel                = 50714
+	tagWhiteLevel                = 50717
+	tagColorMatrix1              = 50721
+	tagAsShotNeutral             = 50728
+	tagCalibrationIlluminant1    = 50778
+
+	photometricRGB       = 2
+	photometricYCbCr     = 6
+	photometricCFA       = 32803
+	illuminantD65        = 21
+	tileSize             = 256
+	uncompressedStripRow = 64
+)
+
+// Encode writes img as a DNG. With a preview, IFD0 holds the preview and
+// the raw data goes into a SubIFD, which is the layout Adobe tools write.
+func Encode(w io.Writer, img *Image, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if len(img.Pix) != img.Width*img.Height {
+		return fmt.Errorf("dng: got %d samples for a %dx%d image", len(img.Pix), img.Width, img.Height)
+	}
+	if len(img.CFA) == 0 || len(img.CFA[0]) == 0 {
+		return fmt.Errorf("dng: missing CFA pattern")
+	}
+
+	raw, err := rawIFD(img, opts.Compression)
+	if err != nil {
+		return err
+	}
+
+	ifd0 := raw
+	if p := opts.Preview; p != nil {
+		ifd0 = previewIFD(p)
+		ifd0.Sub(tiffio.TagSubIFDs, raw)
+	}
+
+	ifd0.Byte(tagDNGVersion, 1, 4, 0, 0)
+	ifd0.Byte(tagDNGBackwardVersion, 1, 1, 0, 0)
+	ifd0.Short(exif.TagOrientation, uint16(max(1, img.Orientation)))
+	exif.AddTags(ifd0, opts.Metadata)
+	model := "Unknown"
+	if m := opts.Metadata; m != nil && m.IData.Model != "" {
+		model = m.IData.Make + " " + m.IData.Model
+	}
+	ifd0.ASCII(tagUniqueCameraModel, model)
+
+	ifd0.SRational(tagColorMatrix1,
+		srational(img.ColorMatrix[0][0]), srational(img.ColorMatrix[0][1]), srational(img.ColorMatrix[0][2]),
+		srational(img.ColorMatrix[1][0]), srational(img.ColorMatrix[1][1]), srational(img.ColorMatrix[1][2]),
+		srational(img.ColorMatrix[2][0]), srational(img.ColorMatrix[2][1]), srational(img.ColorMatrix[2][2]),
+	)
+	ifd0.Short(tagCalibrationIlluminant1, illuminantD65)
+	if n := img.AsShotNeutral; n[0] > 0 && n[1] > 0 && n[2] > 0 {
+		ifd0.Rational(tagAsShotNeutral, rational(n[0]), rational(n[1]), rational(n[2]))
+	}
+
+	return tiffio.Encode(w, ifd0)
+}
+
+func rawIFD(img *Image, compression Compression) (*tiffio.IFD, error) {
+	ifd := &tiffio.IFD{}
+	ifd.Long(tagNewSubfileType, 0)
+	ifd.Long(tagImageWidth, uint32(img.Width))
+	ifd.Long(tagImageLength, uint32(img.Height))
+	ifd.Short(tagBitsPerSample, 16)
+	ifd.Short(tagPhotometricInterpretation, photometricCFA)
+	ifd.Short(tagSamplesPerPixel, 1)
+	ifd.Short(tagPlanarConfiguration, 1)
+
+	rows, cols := len(img.CFA), len(img.CFA[0])
+	ifd.Short(tagCFARepeatPatternDim, uint16(rows), uint16(cols))
+	pattern := make([]uint8, 0, rows*cols)
+	for _, r := range img.CFA {
+		if len(r) != cols {
+			return nil, fmt.Errorf("dng: CFA pattern rows differ in length")
+		}
+		pattern = append(pattern, r...)
+	}
+	ifd.Byte(tagCFAPattern, pattern...)
+	ifd.Byte(tagCFAPlaneColor, Red, Green, Blue)
+	ifd.Short(tagCFALayout, 1)
+
+	black := img.BlackLevel
+	if len(black) == 0 || len(black[0]) == 0 {
+		black = [][]uint32{{0}}
+	}
+	ifd.Short(tagBlackLevelRepeatDim, uint16(len(black)), uint16(len(black[0])))
+	var levels []uint32
+	for _, r := range black {
+		levels = append(levels, r...)
+	}
+	ifd.Long(tagBlackLevel, levels...)
+	white := img.WhiteLevel
+	if white == 0 {
+		white = math.MaxUint16
+	}
+	ifd.Long(tagWhiteLevel, white)
+
+	switch compression {
+	case Uncompressed:
+		ifd.Short(tagCompression, 1)
+		ifd.Long(tagRowsPerStrip, uncompressedStripRow)
+		for y := 0; y < img.Height; y += uncompressedStripRow {
+			end := min(y+uncompressedStripRow, img.Height)
+			strip := make([]byte, 0, 2*img.Width*(end-y))
+			for _, v := range img.Pix[y*img.Width : end*img.Width] {
+				strip = append(strip, byte(v), byte(v>>8))
+			}
+			ifd.Strips = append(ifd.Strips, strip)
+		}
+	case LosslessJPEG:
+		ifd.Short(tagCompression, 7)
+		ifd.Long(tagTileWidth, tileSize)
+		ifd.Long(tagTileLength, tileSize)
+		ifd.Tiled = true
+		peak := white
+		for _, v := range img.Pix {
+			peak = max(peak, uint32(v))
+		}
+		precision := max(2, bitLength(peak))
+		tile := make([]uint16, tileSize*tileSize)
+		for ty := 0; ty < img.Height; ty += tileSize {
+			for tx := 0; tx < img.Width; tx += tileSize {
+				copyTile(tile, img, tx, ty)
+				ifd.Strips = append(ifd.Strips, encodeLJ92(tile, tileSize, tileSize, precision))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("dng: unknown compression %d", compression)
+	}
+
+	return ifd, nil
+}
+
+// copyTile extracts a tile, replicating the last row and column into the
+// padding of tiles that extend past the image.
+func copyTile(tile []uint16, img *Image, tx, ty int) {
+	for y := range tileSize {
+		sy := min(ty+y, img.Height-1)
+		for x := range tileSize {
+			sx := min(tx+x, img.Width-1)
+			tile[y*tileSize+x] = img.Pix[sy*img.Width+sx]
+		}
+	}
+}
+
+func previewIFD(p *Preview) *tiffio.IFD {
+	ifd := &tiffio.IFD{}
+	ifd.Long(tagNewSubfileType, 1)
+	ifd.Long(tagImageWidth, uint32(p.Width))
+	ifd.Long(tagImageLength, uint32(p.Height))
+	ifd.Short(tagBitsPerSample, 8, 8, 8)
+	ifd.Short(tagSamplesPerPixel, 3)
+	ifd.Short(tagPlanarConfiguration, 1)
+	if p.JPEG {
+		ifd.Short(tagCompression, 7)
+		ifd.Short(tagPhotometricInterpretation, photometricYCbCr)
+		ifd.Long(tagRowsPerStrip, uint32(p.Height))
+	} else {
+		ifd.Short(tagCompression, 1)
+		ifd.Short(tagPhotometricInterpretation, photometricRGB)
+		ifd.Long(tagRowsPerStrip, uint32(p.Height))
+	}
+	ifd.Strips = [][]byte{p.Data}
+	return ifd
+}
+
+func bitLength(v uint32) int {
+	n := 0
+	for v > 0 {
+		n++
+		v >>= 1
+	}
+	return n
+}
+
+func srational(v float64) [2]int32 {
+	return [2]int32{int32(math.Round(v * 10000)), 10000}
+}
+
+func rational(v float64) [2]uint32 {
+	return [2]uint32{uint32(math.Round(v * 1000000)), 1000000}
+}
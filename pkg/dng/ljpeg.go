@@ -0,0 +1,228 @@
+package dng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+)
+
+// encodeLJ92 compresses a single component image with lossless JPEG
+// (ITU T.81 process 14, SOF3) using predictor 1 and a Huffman table
+// optimized for the data, which is how DNG stores compressed raw tiles.
+func encodeLJ92(pix []uint16, width, height, precision int) []byte {
+	diffs := make([]int32, len(pix))
+	var freq [17]int
+	for y := range height {
+		for x := range width {
+			var pred int32
+			switch {
+			case x == 0 && y == 0:
+				pred = 1 << (precision - 1)
+			case y == 0 || x > 0:
+				pred = int32(pix[y*width+x-1])
+			default:
+				pred = int32(pix[(y-1)*width])
+			}
+			// differences are taken modulo 2^16 (T.81 H.1.2.1)
+			d := int32(int16(uint16(int32(pix[y*width+x]) - pred)))
+			if d == -32768 {
+				d = 32768
+			}
+			diffs[y*width+x] = d
+			freq[category(d)]++
+		}
+	}
+
+	bits, huffval := huffmanTable(freq[:])
+	codes := canonicalCodes(bits, huffval)
+
+	var out bytes.Buffer
+	out.Write([]byte{0xff, 0xd8}) // SOI
+
+	// DHT, table class 0 (DC/lossless), id 0
+	marker(&out, 0xc4, append(append([]byte{0x00}, bits[1:]...), huffval...))
+
+	// SOF3: precision, height, width, one component, 1x1 sampling
+	sof := []byte{byte(precision), 0, 0, 0, 0, 1, 1, 0x11, 0}
+	binary.BigEndian.PutUint16(sof[1:], uint16(height))
+	binary.BigEndian.PutUint16(sof[3:], uint16(width))
+	marker(&out, 0xc3, sof)
+
+	// SOS: component 1 with table 0, predictor 1, no point transform
+	marker(&out, 0xda, []byte{1, 1, 0x00, 1, 0, 0})
+
+	bw := bitWriter{out: &out}
+	for _, d := range diffs {
+		s := category(d)
+		c := codes[s]
+		bw.write(c.code, c.size)
+		if s == 0 || s == 16 {
+			continue
+		}
+		v := d
+		if v < 0 {
+			v--
+		}
+		bw.write(uint32(v)&(1<<s-1), s)
+	}
+	bw.flush()
+
+	out.Write([]byte{0xff, 0xd9}) // EOI
+	return out.Bytes()
+}
+
+func marker(out *bytes.Buffer, m byte, payload []byte) {
+	out.Write([]byte{0xff, m})
+	binary.Write(out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+}
+
+// category returns the SSSS difference category: the bit length of |d|.
+func category(d int32) int {
+	if d < 0 {
+		d = -d
+	}
+	n := 0
+	for d > 0 {
+		n++
+		d >>= 1
+	}
+	return n
+}
+
+// huffmanTable builds a length limited table following T.81 Annex K.2.
+// It returns BITS (index 1..16 holds the number of codes of that length)
+// and HUFFVAL (symbols ordered by code length).
+func huffmanTable(freq []int) ([]byte, []byte) {
+	n := len(freq)
+	f := make([]int, n+1)
+	copy(f, freq)
+	f[n] = 1 // reserved symbol so no code is all ones
+
+	codesize := make([]int, n+1)
+	others := make([]int, n+1)
+	for i := range others {
+		others[i] = -1
+	}
+
+	for {
+		v1, v2 := -1, -1
+		for i, fi := range f {
+			if fi > 0 && (v1 < 0 || fi <= f[v1]) {
+				v1 = i
+			}
+		}
+		for i, fi := range f {
+			if fi > 0 && i != v1 && (v2 < 0 || fi <= f[v2]) {
+				v2 = i
+			}
+		}
+		if v2 < 0 {
+			break
+		}
+
+		f[v1] += f[v2]
+		f[v2] = 0
+		codesize[v1]++
+		for others[v1] >= 0 {
+			v1 = others[v1]
+			codesize[v1]++
+		}
+		others[v1] = v2
+		codesize[v2]++
+		for others[v2] >= 0 {
+			v2 = others[v2]
+			codesize[v2]++
+		}
+	}
+
+	count := make([]int, 33)
+	for _, s := range codesize {
+		if s > 0 {
+			count[s]++
+		}
+	}
+	// limit code lengths to 16 bits (Figure K.3)
+	for i := 32; i > 16; i-- {
+		for count[i] > 0 {
+			j := i - 2
+			for count[j] == 0 {
+				j--
+			}
+			count[i] -= 2
+			count[i-1]++
+			count[j+1] += 2
+			count[j]--
+		}
+	}
+	// drop the reserved symbol from the longest length
+	for i := 16; i > 0; i-- {
+		if count[i] > 0 {
+			count[i]--
+			break
+		}
+	}
+
+	bits := make([]byte, 17)
+	for i := 1; i <= 16; i++ {
+		bits[i] = byte(count[i])
+	}
+
+	var huffval []byte
+	symbols := make([]int, 0, n)
+	for i := range n {
+		if freq[i] > 0 {
+			symbols = append(symbols, i)
+		}
+	}
+	slices.SortStableFunc(symbols, func(a, b int) int { return codesize[a] - codesize[b] })
+	for _, s := range symbols {
+		huffval = append(huffval, byte(s))
+	}
+	return bits, huffval
+}
+
+type huffCode struct {
+	code uint32
+	size int
+}
+
+func canonicalCodes(bits, huffval []byte) map[int]huffCode {
+	codes := map[int]huffCode{}
+	code, k := uint32(0), 0
+	for size := 1; size <= 16; size++ {
+		for range int(bits[size]) {
+			codes[int(huffval[k])] = huffCode{code, size}
+			code++
+			k++
+		}
+		code <<= 1
+	}
+	return codes
+}
+
+type bitWriter struct {
+	out  *bytes.Buffer
+	acc  uint64
+	nacc int
+}
+
+func (w *bitWriter) write(v uint32, n int) {
+	w.acc = w.acc<<n | uint64(v)
+	w.nacc += n
+	for w.nacc >= 8 {
+		b := byte(w.acc >> (w.nacc - 8))
+		w.out.WriteByte(b)
+		if b == 0xff {
+			w.out.WriteByte(0) // byte stuffing
+		}
+		w.nacc -= 8
+	}
+}
+
+// flush pads the last byte with one bits.
+func (w *bitWriter) flush() {
+	if w.nacc > 0 {
+		w.write(1<<(8-w.nacc)-1, 8-w.nacc)
+	}
+}
@@ -0,0 +1,162 @@
+package dng
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"testing"
+)
+
+// decodeLJ92 is a minimal decoder for the streams written by encodeLJ92:
+// one component, one Huffman table, predictor 1.
+func decodeLJ92(t *testing.T, data []byte) ([]uint16, int, int) {
+	t.Helper()
+
+	var bits, huffval []byte
+	var precision, width, height int
+	pos := 2
+	for {
+		if data[pos] != 0xff {
+			t.Fatalf("expected marker at %d", pos)
+		}
+		m := data[pos+1]
+		n := int(binary.BigEndian.Uint16(data[pos+2:]))
+		seg := data[pos+4 : pos+2+n]
+		pos += 2 + n
+		switch m {
+		case 0xc4:
+			bits = append([]byte{0}, seg[1:17]...)
+			huffval = seg[17:]
+		case 0xc3:
+			precision = int(seg[0])
+			height = int(binary.BigEndian.Uint16(seg[1:]))
+			width = int(binary.BigEndian.Uint16(seg[3:]))
+		}
+		if m == 0xda {
+			break
+		}
+	}
+
+	codes := canonicalCodes(bits, huffval)
+	lookup := map[huffCode]int{}
+	for s, c := range codes {
+		lookup[c] = s
+	}
+
+	var acc uint64
+	nacc := 0
+	readBit := func() uint32 {
+		if nacc == 0 {
+			b := data[pos]
+			pos++
+			if b == 0xff {
+				pos++ // stuffed zero
+			}
+			acc, nacc = uint64(b), 8
+		}
+		nacc--
+		return uint32(acc>>nacc) & 1
+	}
+	readBits := func(n int) uint32 {
+		var v uint32
+		for range n {
+			v = v<<1 | readBit()
+		}
+		return v
+	}
+
+	pix := make([]uint16, width*height)
+	for i := range pix {
+		var c huffCode
+		s, ok := 0, false
+		for !ok {
+			c.code = c.code<<1 | readBit()
+			c.size++
+			if c.size > 16 {
+				t.Fatalf("invalid Huffman code at pixel %d", i)
+			}
+			s, ok = lookup[c]
+		}
+
+		var d int32
+		switch {
+		case s == 16:
+			d = 32768
+		case s > 0:
+			v := int32(readBits(s))
+			if v < 1<<(s-1) {
+				v -= 1<<s - 1
+			}
+			d = v
+		}
+
+		x, y := i%width, i/width
+		var pred int32
+		switch {
+		case i == 0:
+			pred = 1 << (precision - 1)
+		case y == 0 || x > 0:
+			pred = int32(pix[i-1])
+		default:
+			pred = int32(pix[i-width])
+		}
+		pix[i] = uint16(pred + d)
+	}
+	return pix, width, height
+}
+
+func TestLJ92RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const w, h = 67, 41
+
+	cases := map[string]func(i int) uint16{
+		"constant": func(int) uint16 { return 512 },
+		"noise14":  func(int) uint16 { return uint16(rng.Intn(1 << 14)) },
+		"extremes": func(i int) uint16 { return uint16(i%2) * 0xffff },
+		"half":     func(i int) uint16 { return uint16(i%2) * 0x8000 },
+		"gradient": func(i int) uint16 { return uint16(i * 7) },
+	}
+	for name, gen := range cases {
+		pix := make([]uint16, w*h)
+		for i := range pix {
+			pix[i] = gen(i)
+		}
+
+		got, gw, gh := decodeLJ92(t, encodeLJ92(pix, w, h, 16))
+		if gw != w || gh != h {
+			t.Fatalf("%s: decoded size %dx%d, want %dx%d", name, gw, gh, w, h)
+		}
+		for i := range pix {
+			if got[i] != pix[i] {
+				t.Fatalf("%s: pixel %d = %d, want %d", name, i, got[i], pix[i])
+			}
+		}
+	}
+}
+
+func TestHuffmanTableLimitsCodeLength(t *testing.T) {
+	// Fibonacci frequencies force code lengths beyond 16 before limiting.
+	freq := make([]int, 17)
+	a, b := 1, 1
+	for i := range freq {
+		freq[i] = a
+		a, b = b, a+b
+	}
+
+	bits, huffval := huffmanTable(freq)
+	total := 0
+	for _, n := range bits[1:] {
+		total += int(n)
+	}
+	if total != len(freq) || len(huffval) != len(freq) {
+		t.Fatalf("table has %d codes and %d values, want %d", total, len(huffval), len(freq))
+	}
+
+	// Kraft inequality, strict because the all ones code is reserved.
+	sum := 0.0
+	for size := 1; size <= 16; size++ {
+		sum += float64(bits[size]) / float64(uint(1)<<size)
+	}
+	if sum >= 1 {
+		t.Errorf("Kraft sum %v >= 1", sum)
+	}
+}
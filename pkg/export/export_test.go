@@ -0,0 +1,226 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stmtc233/go-libraw/pkg/icc"
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+func testImage() *image.RGBA64 {
+	img := image.NewRGBA64(image.Rect(0, 0, 64, 48))
+	for y := range 48 {
+		for x := range 64 {
+			img.SetRGBA64(x, y, color.RGBA64{uint16(x * 1000), uint16(y * 1300), 0x1234, 0xffff})
+		}
+	}
+	return img
+}
+
+func TestEncodePPMHeader(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodePPM(&buf, testImage(), 16); err != nil {
+		t.Fatal(err)
+	}
+
+	header := "P6\n64 48\n65535\n"
+	if got := buf.String()[:len(header)]; got != header {
+		t.Fatalf("header = %q, want %q", got, header)
+	}
+	if n := buf.Len() - len(header); n != 64*48*3*2 {
+		t.Fatalf("got %d bytes of samples", n)
+	}
+	// second pixel, big-endian red sample
+	if v := binary.BigEndian.Uint16(buf.Bytes()[len(header)+6:]); v != 1000 {
+		t.Errorf("red sample of pixel (1,0) = %d, want 1000", v)
+	}
+}
+
+func TestEncodePNGEmbedsProfile(t *testing.T) {
+	profile := icc.Profile(icc.AdobeRGBSpace, icc.BT709)
+
+	var buf bytes.Buffer
+	if err := EncodePNG(&buf, testImage(), profile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("PNG with iCCP does not decode: %v", err)
+	}
+
+	data := buf.Bytes()[8:]
+	for len(data) >= 12 {
+		n := binary.BigEndian.Uint32(data)
+		if string(data[4:8]) == "iCCP" {
+			chunk := data[8 : 8+n]
+			compressed := chunk[bytes.IndexByte(chunk, 0)+2:]
+			zr, err := zlib.NewReader(bytes.NewReader(compressed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := io.ReadAll(zr)
+			if !bytes.Equal(got, profile) {
+				t.Error("embedded profile differs")
+			}
+			return
+		}
+		data = data[12+n:]
+	}
+	t.Fatal("no iCCP chunk written")
+}
+
+func TestEncodeJPEGEmbedsProfile(t *testing.T) {
+	profile := icc.Profile(icc.ProPhotoRGBSpace, icc.Gamma18)
+
+	var buf bytes.Buffer
+	if err := EncodeJPEGWithProfile(&buf, testImage(), profile, 90); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("JPEG with APP2 does not decode: %v", err)
+	}
+
+	seg := buf.Bytes()[2:]
+	if seg[0] != 0xff || seg[1] != 0xe2 || !bytes.HasPrefix(seg[4:], []byte("ICC_PROFILE\x00\x01\x01")) {
+		t.Fatal("no ICC_PROFILE APP2 segment after SOI")
+	}
+	n := int(binary.BigEndian.Uint16(seg[2:]))
+	if got := seg[4+14 : 2+n]; !bytes.Equal(got, profile) {
+		t.Error("embedded profile differs")
+	}
+}
+
+func TestGrayImagesSkipRGBProfile(t *testing.T) {
+	var buf bytes.Buffer
+	gray := image.NewGray(image.Rect(0, 0, 8, 8))
+	if err := EncodeJPEGWithProfile(&buf, gray, icc.Profile(icc.SRGBSpace, icc.SRGB), 90); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("ICC_PROFILE")) {
+		t.Error("RGB profile embedded in grayscale JPEG")
+	}
+}
+
+// ifdTags returns the tags of the IFD at off in a little-endian TIFF body
+// and the offsets of their values.
+func ifdTags(body []byte, off uint32) map[uint16][]byte {
+	tags := map[uint16][]byte{}
+	n := int(binary.LittleEndian.Uint16(body[off:]))
+	for i := range n {
+		e := body[int(off)+2+12*i:]
+		tags[binary.LittleEndian.Uint16(e)] = e[8:12]
+	}
+	return tags
+}
+
+func TestEncodeJPEGWritesExif(t *testing.T) {
+	date := time.Date(2024, 5, 17, 10, 30, 0, 0, time.UTC)
+	meta := &metadata.ImgMetadata{
+		CaptureTimestamp: date.Unix(),
+		CaptureDate:      date,
+		IData:            metadata.LibRawIData{Make: "Canon", Model: "EOS R5"},
+		Other: metadata.ImgOther{
+			ISOSpeed: 400, Shutter: 1.0 / 250, Aperture: 5.6, FocalLength: 35,
+			GPS: metadata.GPSInfo{Latitude: [3]float32{48, 51, 29.5}, LatitudeRef: 'N', Parsed: true},
+		},
+		Lens: metadata.LensInfo{Model: "RF24-105mm F4 L IS USM"},
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeJPEG(&buf, testImage(), meta, 90); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("JPEG with APP1 does not decode: %v", err)
+	}
+
+	body := exifBody(t, buf.Bytes())
+	ifd0 := ifdTags(body, binary.LittleEndian.Uint32(body[4:]))
+	if v, ok := ifd0[274]; !ok || binary.LittleEndian.Uint16(v) != 1 {
+		t.Error("orientation is not 1")
+	}
+	if v, ok := ifd0[271]; !ok || string(body[binary.LittleEndian.Uint32(v):][:5]) != "Canon" {
+		t.Error("make not written")
+	}
+
+	exifIFD := ifdTags(body, binary.LittleEndian.Uint32(ifd0[34665]))
+	v, ok := exifIFD[33434]
+	if !ok {
+		t.Fatal("no exposure time")
+	}
+	r := body[binary.LittleEndian.Uint32(v):]
+	if num, den := binary.LittleEndian.Uint32(r), binary.LittleEndian.Uint32(r[4:]); num != 1 || den != 250 {
+		t.Errorf("exposure time = %d/%d, want 1/250", num, den)
+	}
+	for _, tag := range []uint16{33437, 34855, 37386, 42036, 36867} {
+		if _, ok := exifIFD[tag]; !ok {
+			t.Errorf("EXIF tag %d missing", tag)
+		}
+	}
+
+	gps := ifdTags(body, binary.LittleEndian.Uint32(ifd0[34853]))
+	if v, ok := gps[1]; !ok || v[0] != 'N' {
+		t.Error("GPS latitude ref not written")
+	}
+}
+
+// exifBody returns the TIFF structure of the EXIF segment of a JPEG.
+func exifBody(t *testing.T, data []byte) []byte {
+	t.Helper()
+	seg := data[2:]
+	if seg[0] != 0xff || seg[1] != 0xe1 || !bytes.HasPrefix(seg[4:], []byte("Exif\x00\x00II")) {
+		t.Fatal("no EXIF APP1 segment after SOI")
+	}
+	return seg[10 : 2+int(binary.BigEndian.Uint16(seg[2:]))]
+}
+
+func TestEncodeJPEGWithoutCaptureTime(t *testing.T) {
+	// what LibRaw reports for a file without a timestamp
+	meta := &metadata.ImgMetadata{
+		CaptureTimestamp: 0,
+		CaptureDate:      time.Unix(0, 0),
+		IData:            metadata.LibRawIData{Make: "Canon", Model: "EOS R5"},
+	}
+	var buf bytes.Buffer
+	if err := EncodeJPEG(&buf, testImage(), meta, 90); err != nil {
+		t.Fatal(err)
+	}
+	body := exifBody(t, buf.Bytes())
+	ifd0 := ifdTags(body, binary.LittleEndian.Uint32(body[4:]))
+	if _, ok := ifd0[306]; ok {
+		t.Error("DateTime written without a capture time")
+	}
+	exifIFD := ifdTags(body, binary.LittleEndian.Uint32(ifd0[34665]))
+	for _, tag := range []uint16{36867, 36868} {
+		if _, ok := exifIFD[tag]; ok {
+			t.Errorf("EXIF date tag %d written without a capture time", tag)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 600, 400))
+	for _, tc := range []struct {
+		size int
+		want image.Point
+	}{
+		{0, image.Pt(600, 400)},
+		{1000, image.Pt(600, 400)},
+		{300, image.Pt(300, 200)},
+	} {
+		if got := Fit(img, tc.size).Bounds().Size(); got != tc.want {
+			t.Errorf("Fit(%d) = %v, want %v", tc.size, got, tc.want)
+		}
+	}
+	if got := Fit(image.NewGray(image.Rect(0, 0, 100, 300)), 150).Bounds().Size(); got != image.Pt(50, 150) {
+		t.Errorf("portrait fit = %v", got)
+	}
+}
@@ -0,0 +1,144 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+
+	"github.com/stmtc233/go-libraw/pkg/internal/exif"
+	"github.com/stmtc233/go-libraw/pkg/internal/tiffio"
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// iccChunkSize is the largest profile fragment that fits in one APP2
+// segment next to its 14 byte header.
+const iccChunkSize = 65535 - 2 - 14
+
+// JPEGOptions are the options of EncodeJPEGWithOptions.
+type JPEGOptions struct {
+	// Quality ranges from 1 to 100; 0 selects jpeg.DefaultQuality.
+	Quality int
+	// ICCProfile is embedded in APP2 segments if it matches the color
+	// space of the image.
+	ICCProfile []byte
+	// Metadata, if set, is written as an EXIF APP1 segment.
+	Metadata *metadata.ImgMetadata
+}
+
+// EncodeJPEG encodes img as a JPEG with an EXIF APP1 segment describing
+// the camera, capture time, exposure, lens and GPS position from meta.
+// The orientation is written as 1, since processed images are already
+// rotated.
+func EncodeJPEG(w io.Writer, img image.Image, meta *metadata.ImgMetadata, quality int) error {
+	return EncodeJPEGWithOptions(w, img, &JPEGOptions{Quality: quality, Metadata: meta})
+}
+
+// EncodeJPEGWithProfile encodes img as a JPEG and embeds profile, when it
+// matches the color space of the image, in APP2 ICC_PROFILE segments.
+func EncodeJPEGWithProfile(w io.Writer, img image.Image, profile []byte, quality int) error {
+	return EncodeJPEGWithOptions(w, img, &JPEGOptions{Quality: quality, ICCProfile: profile})
+}
+
+// EncodeJPEGWithOptions encodes img as a JPEG with any of EXIF metadata and
+// an ICC profile.
+func EncodeJPEGWithOptions(w io.Writer, img image.Image, opts *JPEGOptions) error {
+	if opts == nil {
+		opts = &JPEGOptions{}
+	}
+	quality := opts.Quality
+	if quality == 0 {
+		quality = jpeg.DefaultQuality
+	}
+
+	var segments [][]byte
+	if opts.Metadata != nil {
+		seg, err := exifSegment(opts.Metadata)
+		if err != nil {
+			return err
+		}
+		segments = append(segments, seg)
+	}
+	if profileFits(img, opts.ICCProfile) {
+		chunks, err := iccSegments(opts.ICCProfile)
+		if err != nil {
+			return err
+		}
+		segments = append(segments, chunks...)
+	}
+	return encodeJPEG(w, img, quality, segments)
+}
+
+// encodeJPEG encodes img and inserts the given marker segments right after
+// SOI.
+func encodeJPEG(w io.Writer, img image.Image, quality int, segments [][]byte) error {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return err
+	}
+
+	data := buf.Bytes()
+	if _, err := w.Write(data[:2]); err != nil { // SOI
+		return err
+	}
+	for _, s := range segments {
+		if _, err := w.Write(s); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(data[2:])
+	return err
+}
+
+// jpegSegment builds a marker segment, length included.
+func jpegSegment(marker byte, payload ...[]byte) ([]byte, error) {
+	n := 2
+	for _, p := range payload {
+		n += len(p)
+	}
+	if n > 0xffff {
+		return nil, fmt.Errorf("export: JPEG segment of %d bytes is too large", n)
+	}
+
+	seg := []byte{0xff, marker}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(n))
+	for _, p := range payload {
+		seg = append(seg, p...)
+	}
+	return seg, nil
+}
+
+// exifSegment builds the APP1 segment: the EXIF header followed by a
+// little-endian TIFF structure holding IFD0.
+func exifSegment(meta *metadata.ImgMetadata) ([]byte, error) {
+	ifd0 := &tiffio.IFD{}
+	ifd0.Short(exif.TagOrientation, 1)
+	exif.AddTags(ifd0, meta)
+	return jpegSegment(0xe1, []byte("Exif\x00\x00"), tiffio.EncodeBody(ifd0))
+}
+
+// iccSegments splits a profile over as many APP2 segments as needed.
+func iccSegments(profile []byte) ([][]byte, error) {
+	if len(profile) == 0 {
+		return nil, nil
+	}
+
+	count := (len(profile) + iccChunkSize - 1) / iccChunkSize
+	if count > 255 {
+		return nil, fmt.Errorf("export: ICC profile of %d bytes is too large for JPEG", len(profile))
+	}
+
+	var segments [][]byte
+	for i := range count {
+		chunk := profile[i*iccChunkSize : min(len(profile), (i+1)*iccChunkSize)]
+		header := append([]byte("ICC_PROFILE\x00"), byte(i+1), byte(count))
+		seg, err := jpegSegment(0xe2, header, chunk)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+	return segments, nil
+}
@@ -0,0 +1,78 @@
+package export
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+)
+
+// depthOf reports the natural sample depth of img.
+func depthOf(img image.Image) int {
+	switch img.(type) {
+	case *image.RGBA64, *image.NRGBA64, *image.Gray16:
+		return 16
+	}
+	return 8
+}
+
+func isGray(img image.Image) bool {
+	switch img.(type) {
+	case *image.Gray, *image.Gray16:
+		return true
+	}
+	return false
+}
+
+// profileFits reports whether an ICC profile describes the color space of
+// img, so a grayscale image never gets tagged with an RGB profile.
+func profileFits(img image.Image, profile []byte) bool {
+	if len(profile) < 20 {
+		return false
+	}
+	if isGray(img) {
+		return string(profile[16:20]) == "GRAY"
+	}
+	return string(profile[16:20]) == "RGB "
+}
+
+// appendRow appends row y of img to dst as interleaved samples (1 for
+// grayscale images, 3 for RGB) of the given bit depth. The common
+// LibRaw output types are copied directly so that values round-trip
+// exactly; everything else goes through the color model.
+func appendRow(dst []byte, img image.Image, y, samples, bits int, order binary.AppendByteOrder) []byte {
+	b := img.Bounds()
+
+	switch m := img.(type) {
+	case *image.RGBA:
+		if bits == 8 && samples == 3 {
+			p := m.Pix[m.PixOffset(b.Min.X, y):]
+			for x := range b.Dx() {
+				dst = append(dst, p[4*x], p[4*x+1], p[4*x+2])
+			}
+			return dst
+		}
+	case *image.Gray:
+		if bits == 8 && samples == 1 {
+			i := m.PixOffset(b.Min.X, y)
+			return append(dst, m.Pix[i:i+b.Dx()]...)
+		}
+	}
+
+	for x := b.Min.X; x < b.Max.X; x++ {
+		var v [3]uint16
+		if samples == 1 {
+			v[0] = color.Gray16Model.Convert(img.At(x, y)).(color.Gray16).Y
+		} else {
+			c := color.RGBA64Model.Convert(img.At(x, y)).(color.RGBA64)
+			v = [3]uint16{c.R, c.G, c.B}
+		}
+		for _, s := range v[:samples] {
+			if bits == 8 {
+				dst = append(dst, uint8(s>>8))
+			} else {
+				dst = order.AppendUint16(dst, s)
+			}
+		}
+	}
+	return dst
+}
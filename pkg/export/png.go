@@ -0,0 +1,58 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+)
+
+// pngIHDREnd is the offset just past the IHDR chunk, which image/png
+// always writes first: 8 byte signature, then 4+4+13+4 bytes of IHDR.
+const pngIHDREnd = 8 + 25
+
+// EncodePNG encodes img as a PNG (16-bit for RGBA64/Gray16 images) and
+// embeds profile, when it matches the color space of the image, as an
+// iCCP chunk.
+func EncodePNG(w io.Writer, img image.Image, profile []byte) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	if !profileFits(img, profile) {
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	if _, err := zw.Write(profile); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	// profile name, NUL, compression method 0 (deflate), compressed profile
+	iccp := append([]byte("ICC Profile\x00\x00"), z.Bytes()...)
+
+	data := buf.Bytes()
+	if _, err := w.Write(data[:pngIHDREnd]); err != nil {
+		return err
+	}
+	if _, err := w.Write(pngChunk("iCCP", iccp)); err != nil {
+		return err
+	}
+	_, err := w.Write(data[pngIHDREnd:])
+	return err
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, typ...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
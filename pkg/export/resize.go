@@ -0,0 +1,24 @@
+package export
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// Fit scales img down with Catmull-Rom to fit within size x size pixels,
+// keeping the aspect ratio. Images already small enough, and any size <= 0,
+// return img unchanged.
+func Fit(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	if size <= 0 || max(b.Dx(), b.Dy()) <= size {
+		return img
+	}
+	w, h := size, max(b.Dy()*size/b.Dx(), 1)
+	if b.Dy() > b.Dx() {
+		w, h = max(b.Dx()*size/b.Dy(), 1), size
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Rect, img, b, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,160 @@
+// Package export encodes images produced by the LibRaw binding into file
+// formats, carrying along ICC profiles and camera metadata.
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/stmtc233/go-libraw/pkg/internal/exif"
+	"github.com/stmtc233/go-libraw/pkg/internal/tiffio"
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// Compression selects how TIFF strips are stored.
+type Compression int
+
+const (
+	Uncompressed Compression = iota
+	Deflate                  // Adobe deflate with horizontal predictor
+)
+
+// TIFFOptions configures EncodeTIFF.
+type TIFFOptions struct {
+	Compression Compression
+	// Bits is 8 or 16. When zero the depth of the image is used: 16 for
+	// RGBA64/Gray16 images and 8 otherwise.
+	Bits int
+	// ICCProfile is embedded as-is (tag 34675) when it matches the color
+	// space of the image.
+	ICCProfile []byte
+	// Metadata fills Make, Model, Software and the EXIF capture time.
+	Metadata *metadata.ImgMetadata
+}
+
+// Baseline TIFF tags.
+const (
+	tagImageWidth                = 256
+	tagImageLength               = 257
+	tagBitsPerSample             = 258
+	tagCompression               = 259
+	tagPhotometricInterpretation = 262
+	tagSamplesPerPixel           = 277
+	tagRowsPerStrip              = 278
+	tagXResolution               = 282
+	tagYResolution               = 283
+	tagPlanarConfiguration       = 284
+	tagResolutionUnit            = 296
+	tagPredictor                 = 317
+	tagICCProfile                = 34675
+)
+
+const stripSize = 64 << 10
+
+// EncodeTIFF writes img as a baseline RGB (or grayscale) TIFF.
+func EncodeTIFF(w io.Writer, img image.Image, opts *TIFFOptions) error {
+	if opts == nil {
+		opts = &TIFFOptions{}
+	}
+
+	bits := opts.Bits
+	if bits == 0 {
+		bits = depthOf(img)
+	}
+	if bits != 8 && bits != 16 {
+		return fmt.Errorf("export: unsupported TIFF bit depth %d", bits)
+	}
+
+	b := img.Bounds()
+	samples := 3
+	photometric := uint16(2) // RGB
+	if isGray(img) {
+		samples, photometric = 1, 1 // BlackIsZero
+	}
+
+	rowSize := b.Dx() * samples * bits / 8
+	rowsPerStrip := max(1, stripSize/max(1, rowSize))
+
+	ifd := &tiffio.IFD{}
+	ifd.Long(tagImageWidth, uint32(b.Dx()))
+	ifd.Long(tagImageLength, uint32(b.Dy()))
+	bps := make([]uint16, samples)
+	for i := range bps {
+		bps[i] = uint16(bits)
+	}
+	ifd.Short(tagBitsPerSample, bps...)
+	ifd.Short(tagPhotometricInterpretation, photometric)
+	ifd.Short(tagSamplesPerPixel, uint16(samples))
+	ifd.Long(tagRowsPerStrip, uint32(rowsPerStrip))
+	ifd.Rational(tagXResolution, [2]uint32{300, 1})
+	ifd.Rational(tagYResolution, [2]uint32{300, 1})
+	ifd.Short(tagResolutionUnit, 2) // inch
+	ifd.Short(tagPlanarConfiguration, 1)
+
+	switch opts.Compression {
+	case Uncompressed:
+		ifd.Short(tagCompression, 1)
+	case Deflate:
+		ifd.Short(tagCompression, 8)
+		ifd.Short(tagPredictor, 2)
+	default:
+		return fmt.Errorf("export: unknown TIFF compression %d", opts.Compression)
+	}
+
+	if profileFits(img, opts.ICCProfile) {
+		ifd.Undefined(tagICCProfile, opts.ICCProfile)
+	}
+	// LibRaw has already applied the rotation to the pixels
+	ifd.Short(exif.TagOrientation, 1)
+	exif.AddTags(ifd, opts.Metadata)
+
+	for y := b.Min.Y; y < b.Max.Y; y += rowsPerStrip {
+		rows := min(rowsPerStrip, b.Max.Y-y)
+		strip := make([]byte, 0, rows*rowSize)
+		for r := range rows {
+			strip = appendRow(strip, img, y+r, samples, bits, binary.LittleEndian)
+		}
+
+		if opts.Compression == Deflate {
+			var err error
+			if strip, err = deflate(strip, rowSize, samples, bits); err != nil {
+				return err
+			}
+		}
+		ifd.Strips = append(ifd.Strips, strip)
+	}
+
+	return tiffio.Encode(w, ifd)
+}
+
+// deflate applies the horizontal differencing predictor to each row in
+// place and zlib-compresses the strip.
+func deflate(strip []byte, rowSize, samples, bits int) ([]byte, error) {
+	for row := 0; row < len(strip); row += rowSize {
+		r := strip[row : row+rowSize]
+		if bits == 8 {
+			for i := len(r) - 1; i >= samples; i-- {
+				r[i] -= r[i-samples]
+			}
+			continue
+		}
+		for i := len(r)/2 - 1; i >= samples; i-- {
+			v := binary.LittleEndian.Uint16(r[2*i:]) - binary.LittleEndian.Uint16(r[2*(i-samples):])
+			binary.LittleEndian.PutUint16(r[2*i:], v)
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(strip); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,180 @@
+package icc
+
+// Chromaticity is a CIE 1931 xy coordinate.
+type Chromaticity struct {
+	X, Y float64
+}
+
+// Standard white points.
+var (
+	D50 = Chromaticity{0.3457, 0.3585}
+	D60 = Chromaticity{0.32168, 0.33767}
+	D65 = Chromaticity{0.3127, 0.3290}
+)
+
+// ColorSpace describes an RGB output space by its primaries and white point.
+// Curve is the tone curve the space is defined with; LibRaw always encodes
+// with the Gamm curve of the processor options, so profiles for rendered
+// images are built from that instead.
+type ColorSpace struct {
+	Name  string
+	Red   Chromaticity
+	Green Chromaticity
+	Blue  Chromaticity
+	White Chromaticity
+	Curve ToneCurve
+
+	// identity marks CIE XYZ itself, which has no meaningful primaries.
+	identity bool
+}
+
+// The color spaces LibRaw can render into, in output_color order.
+var (
+	SRGBSpace = ColorSpace{
+		Name: "sRGB",
+		Red:  Chromaticity{0.64, 0.33}, Green: Chromaticity{0.30, 0.60}, Blue: Chromaticity{0.15, 0.06},
+		White: D65,
+		Curve: SRGB,
+	}
+	AdobeRGBSpace = ColorSpace{
+		Name: "Adobe RGB (1998)",
+		Red:  Chromaticity{0.64, 0.33}, Green: Chromaticity{0.21, 0.71}, Blue: Chromaticity{0.15, 0.06},
+		White: D65,
+		Curve: Gamma22,
+	}
+	WideGamutRGBSpace = ColorSpace{
+		Name: "Wide Gamut RGB",
+		Red:  Chromaticity{0.7347, 0.2653}, Green: Chromaticity{0.1152, 0.8264}, Blue: Chromaticity{0.1566, 0.0177},
+		White: D50,
+		Curve: Gamma22,
+	}
+	ProPhotoRGBSpace = ColorSpace{
+		Name: "ProPhoto RGB",
+		Red:  Chromaticity{0.7347, 0.2653}, Green: Chromaticity{0.1596, 0.8404}, Blue: Chromaticity{0.0366, 0.0001},
+		White: D50,
+		Curve: Gamma18,
+	}
+	XYZSpace = ColorSpace{
+		Name:     "XYZ",
+		White:    D65,
+		Curve:    Linear,
+		identity: true,
+	}
+	ACESSpace = ColorSpace{
+		Name: "ACES",
+		Red:  Chromaticity{0.7347, 0.2653}, Green: Chromaticity{0.0, 1.0}, Blue: Chromaticity{0.0001, -0.0770},
+		White: D60,
+		Curve: Linear,
+	}
+	DCIP3Space = ColorSpace{
+		Name: "DCI-P3 D65",
+		Red:  Chromaticity{0.680, 0.320}, Green: Chromaticity{0.265, 0.690}, Blue: Chromaticity{0.150, 0.060},
+		White: D65,
+		Curve: Gamma26,
+	}
+	Rec2020Space = ColorSpace{
+		Name: "Rec. 2020",
+		Red:  Chromaticity{0.708, 0.292}, Green: Chromaticity{0.170, 0.797}, Blue: Chromaticity{0.131, 0.046},
+		White: D65,
+		Curve: BT709,
+	}
+)
+
+// XYZ returns the tristimulus values of the chromaticity at Y = 1.
+func (c Chromaticity) XYZ() [3]float64 {
+	return [3]float64{c.X / c.Y, 1, (1 - c.X - c.Y) / c.Y}
+}
+
+// ToXYZ returns the matrix converting linear RGB in this space to XYZ
+// relative to the space's own white point.
+func (cs ColorSpace) ToXYZ() [3][3]float64 {
+	if cs.identity {
+		return [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	}
+
+	var p [3][3]float64
+	for j, c := range []Chromaticity{cs.Red, cs.Green, cs.Blue} {
+		v := c.XYZ()
+		for i := range 3 {
+			p[i][j] = v[i]
+		}
+	}
+	s := mulVec(invert(p), cs.White.XYZ())
+	for i := range 3 {
+		for j := range 3 {
+			p[i][j] *= s[j]
+		}
+	}
+	return p
+}
+
+// ToXYZD50 returns ToXYZ chromatically adapted to the D50 profile
+// connection space with the Bradford transform.
+func (cs ColorSpace) ToXYZD50() [3][3]float64 {
+	return mul(bradford(cs.White.XYZ(), D50.XYZ()), cs.ToXYZ())
+}
+
+// ConvertTo returns the matrix converting linear RGB in this space to
+// linear RGB in dst, adapting the white point with the Bradford transform.
+func (cs ColorSpace) ConvertTo(dst ColorSpace) [3][3]float64 {
+	return mul(invert(dst.ToXYZD50()), cs.ToXYZD50())
+}
+
+var bradfordMatrix = [3][3]float64{
+	{0.8951, 0.2664, -0.1614},
+	{-0.7502, 1.7135, 0.0367},
+	{0.0389, -0.0685, 1.0296},
+}
+
+func bradford(src, dst [3]float64) [3][3]float64 {
+	s := mulVec(bradfordMatrix, src)
+	d := mulVec(bradfordMatrix, dst)
+	scale := [3][3]float64{{d[0] / s[0], 0, 0}, {0, d[1] / s[1], 0}, {0, 0, d[2] / s[2]}}
+	return mul(invert(bradfordMatrix), mul(scale, bradfordMatrix))
+}
+
+func mul(a, b [3][3]float64) [3][3]float64 {
+	var out [3][3]float64
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				out[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return out
+}
+
+func mulVec(a [3][3]float64, v [3]float64) [3]float64 {
+	var out [3]float64
+	for i := range 3 {
+		for k := range 3 {
+			out[i] += a[i][k] * v[k]
+		}
+	}
+	return out
+}
+
+func invert(m [3][3]float64) [3][3]float64 {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+
+	return [3][3]float64{
+		{
+			(m[1][1]*m[2][2] - m[1][2]*m[2][1]) / det,
+			(m[0][2]*m[2][1] - m[0][1]*m[2][2]) / det,
+			(m[0][1]*m[1][2] - m[0][2]*m[1][1]) / det,
+		},
+		{
+			(m[1][2]*m[2][0] - m[1][0]*m[2][2]) / det,
+			(m[0][0]*m[2][2] - m[0][2]*m[2][0]) / det,
+			(m[0][2]*m[1][0] - m[0][0]*m[1][2]) / det,
+		},
+		{
+			(m[1][0]*m[2][1] - m[1][1]*m[2][0]) / det,
+			(m[0][1]*m[2][0] - m[0][0]*m[2][1]) / det,
+			(m[0][0]*m[1][1] - m[0][1]*m[1][0]) / det,
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package icc
+
+import "math"
+
+// ToneCurve is the gamma curve LibRaw applies to its output, in the same
+// layout as libraw_output_params_t.gamm: Power is gamm[0] (the inverse
+// gamma, e.g. 0.45) and Slope is gamm[1] (the slope of the linear toe,
+// e.g. 4.5). The remaining fields are derived by NewToneCurve exactly like
+// LibRaw's gamma_curve().
+type ToneCurve struct {
+	Power float64
+	Slope float64
+
+	knee   float64 // encoded value where the toe ends (gamm[2])
+	linear float64 // linear value where the toe ends (gamm[3])
+	offset float64 // offset of the power segment (gamm[4])
+}
+
+// Common curves. BT709 is LibRaw's default, Linear is what `dcraw -4` emits.
+var (
+	BT709   = NewToneCurve(0.45, 4.5)
+	SRGB    = NewToneCurve(1/2.4, 12.92)
+	Linear  = NewToneCurve(1, 1)
+	Gamma18 = NewToneCurve(1/1.8, 0)
+	Gamma22 = NewToneCurve(256.0/563, 0) // Adobe RGB: 563/256 = 2.19921875
+	Gamma26 = NewToneCurve(1/2.6, 0)
+)
+
+// Gamm returns the curve in the layout of ProcessorOptions.Gamm.
+func (g ToneCurve) Gamm() [6]float64 {
+	return [6]float64{g.Power, g.Slope}
+}
+
+// NewToneCurve derives the toe knee and offset for a power/slope pair.
+func NewToneCurve(power, slope float64) ToneCurve {
+	g := ToneCurve{Power: power, Slope: slope}
+
+	var bnd [2]float64
+	if slope >= 1 {
+		bnd[1] = 1
+	} else {
+		bnd[0] = 1
+	}
+	if slope != 0 && (slope-1)*(power-1) <= 0 {
+		for range 48 {
+			g.knee = (bnd[0] + bnd[1]) / 2
+			var hi bool
+			if power != 0 {
+				hi = (math.Pow(g.knee/slope, -power)-1)/power-1/g.knee > -1
+			} else {
+				hi = g.knee/math.Exp(1-1/g.knee) < slope
+			}
+			if hi {
+				bnd[1] = g.knee
+			} else {
+				bnd[0] = g.knee
+			}
+		}
+		g.linear = g.knee / slope
+		if power != 0 {
+			g.offset = g.knee * (1/power - 1)
+		}
+	}
+	return g
+}
+
+// IsLinear reports whether the curve is the identity.
+func (g ToneCurve) IsLinear() bool {
+	return g.Power == 1 && g.Slope == 1
+}
+
+// Encode maps a linear value in [0,1] to its encoded value.
+func (g ToneCurve) Encode(v float64) float64 {
+	switch {
+	case v >= 1:
+		return 1
+	case v < g.linear:
+		return v * g.Slope
+	case g.Power != 0:
+		return math.Pow(v, g.Power)*(1+g.offset) - g.offset
+	default:
+		return math.Log(v)*g.knee + 1
+	}
+}
+
+// Decode maps an encoded value in [0,1] back to linear light.
+func (g ToneCurve) Decode(v float64) float64 {
+	switch {
+	case v >= 1:
+		return 1
+	case v < g.knee:
+		return v / g.Slope
+	case g.Power != 0:
+		return math.Pow((v+g.offset)/(1+g.offset), 1/g.Power)
+	default:
+		return math.Exp((v - 1) / g.knee)
+	}
+}
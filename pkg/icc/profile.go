@@ -0,0 +1,144 @@
+// Package icc builds ICC v2 matrix/TRC display profiles describing the
+// color spaces LibRaw renders into, so encoded outputs can be color managed.
+package icc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+const (
+	headerSize  = 128
+	curvePoints = 1024
+)
+
+// Profile returns an ICC v2 RGB profile for the color space whose tone
+// response matches curve. The result is suitable for embedding in TIFF,
+// JPEG (APP2) and PNG (iCCP).
+func Profile(cs ColorSpace, curve ToneCurve) []byte {
+	m := cs.ToXYZD50()
+	white := cs.White.XYZ()
+	trc := curveTag(curve)
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", descTag(description(cs, curve))},
+		{"cprt", textTag("No copyright, use freely")},
+		{"wtpt", xyzTag(white[0], white[1], white[2])},
+		{"rXYZ", xyzTag(m[0][0], m[1][0], m[2][0])},
+		{"gXYZ", xyzTag(m[0][1], m[1][1], m[2][1])},
+		{"bXYZ", xyzTag(m[0][2], m[1][2], m[2][2])},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	var table, body bytes.Buffer
+	binary.Write(&table, binary.BigEndian, uint32(len(tags)))
+	bodyStart := headerSize + 4 + 12*len(tags)
+	offsets := map[*byte]int{}
+	for _, t := range tags {
+		// the three TRC tags share a single copy of the curve
+		off, shared := offsets[&t.data[0]]
+		if !shared {
+			off = bodyStart + body.Len()
+			offsets[&t.data[0]] = off
+			body.Write(t.data)
+			for body.Len()%4 != 0 {
+				body.WriteByte(0)
+			}
+		}
+		table.WriteString(t.sig)
+		binary.Write(&table, binary.BigEndian, uint32(off))
+		binary.Write(&table, binary.BigEndian, uint32(len(t.data)))
+	}
+
+	size := headerSize + table.Len() + body.Len()
+	out := make([]byte, headerSize, size)
+	be := binary.BigEndian
+	be.PutUint32(out[0:], uint32(size))
+	be.PutUint32(out[8:], 0x02100000) // version 2.1
+	copy(out[12:], "mntr")
+	copy(out[16:], "RGB ")
+	copy(out[20:], "XYZ ")
+	copy(out[36:], "acsp")
+	copy(out[48:], "none")
+	d50 := D50.XYZ()
+	be.PutUint32(out[68:], s15f16(d50[0]))
+	be.PutUint32(out[72:], s15f16(d50[1]))
+	be.PutUint32(out[76:], s15f16(d50[2]))
+
+	out = append(out, table.Bytes()...)
+	return append(out, body.Bytes()...)
+}
+
+func description(cs ColorSpace, curve ToneCurve) string {
+	switch {
+	case curve.IsLinear():
+		return cs.Name + " (linear)"
+	case curve == BT709:
+		return cs.Name + " (BT.709 gamma)"
+	case curve == SRGB:
+		return cs.Name + " (sRGB gamma)"
+	case curve == Gamma18:
+		return cs.Name + " (gamma 1.8)"
+	case curve == Gamma22:
+		return cs.Name + " (gamma 2.2)"
+	case curve == Gamma26:
+		return cs.Name + " (gamma 2.6)"
+	}
+	return cs.Name
+}
+
+func s15f16(v float64) uint32 {
+	return uint32(int32(math.Round(v * 65536)))
+}
+
+func xyzTag(x, y, z float64) []byte {
+	b := make([]byte, 20)
+	copy(b, "XYZ ")
+	binary.BigEndian.PutUint32(b[8:], s15f16(x))
+	binary.BigEndian.PutUint32(b[12:], s15f16(y))
+	binary.BigEndian.PutUint32(b[16:], s15f16(z))
+	return b
+}
+
+func textTag(s string) []byte {
+	b := make([]byte, 8, 8+len(s)+1)
+	copy(b, "text")
+	b = append(b, s...)
+	return append(b, 0)
+}
+
+// descTag encodes a v2 textDescriptionType with an ASCII description and
+// empty Unicode and ScriptCode records.
+func descTag(s string) []byte {
+	b := make([]byte, 12, 12+len(s)+1+79)
+	copy(b, "desc")
+	binary.BigEndian.PutUint32(b[8:], uint32(len(s)+1))
+	b = append(b, s...)
+	b = append(b, 0)
+	return append(b, make([]byte, 4+4+2+1+67)...)
+}
+
+// curveTag samples the decoding side of the tone curve, since an ICC TRC
+// maps device values to linear light.
+func curveTag(curve ToneCurve) []byte {
+	if curve.IsLinear() {
+		b := make([]byte, 12)
+		copy(b, "curv")
+		return b
+	}
+
+	b := make([]byte, 12+2*curvePoints)
+	copy(b, "curv")
+	binary.BigEndian.PutUint32(b[8:], curvePoints)
+	for i := range curvePoints {
+		v := curve.Decode(float64(i) / (curvePoints - 1))
+		binary.BigEndian.PutUint16(b[12+2*i:], uint16(math.Round(v*0xffff)))
+	}
+	return b
+}
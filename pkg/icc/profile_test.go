@@ -0,0 +1,117 @@
+package icc
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func readTags(t *testing.T, p []byte) map[string][]byte {
+	t.Helper()
+	be := binary.BigEndian
+	if int(be.Uint32(p)) != len(p) {
+		t.Fatalf("header size %d != profile length %d", be.Uint32(p), len(p))
+	}
+	if string(p[36:40]) != "acsp" {
+		t.Fatalf("missing profile signature")
+	}
+
+	tags := map[string][]byte{}
+	n := int(be.Uint32(p[128:]))
+	for i := range n {
+		e := p[132+12*i:]
+		off, size := int(be.Uint32(e[4:])), int(be.Uint32(e[8:]))
+		if off%4 != 0 || off+size > len(p) {
+			t.Fatalf("tag %s out of bounds: offset %d size %d", e[:4], off, size)
+		}
+		tags[string(e[:4])] = p[off : off+size]
+	}
+	return tags
+}
+
+func s15(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+func TestSRGBColorants(t *testing.T) {
+	tags := readTags(t, Profile(SRGBSpace, SRGB))
+
+	// Published D50-adapted sRGB colorants.
+	want := map[string][3]float64{
+		"rXYZ": {0.4361, 0.2225, 0.0139},
+		"gXYZ": {0.3851, 0.7169, 0.0971},
+		"bXYZ": {0.1431, 0.0606, 0.7141},
+	}
+	for sig, xyz := range want {
+		tag, ok := tags[sig]
+		if !ok {
+			t.Fatalf("missing %s tag", sig)
+		}
+		for i := range 3 {
+			if got := s15(tag[8+4*i:]); math.Abs(got-xyz[i]) > 0.002 {
+				t.Errorf("%s[%d] = %.4f, want %.4f", sig, i, got, xyz[i])
+			}
+		}
+	}
+	for _, sig := range []string{"desc", "cprt", "wtpt", "rTRC", "gTRC", "bTRC"} {
+		if _, ok := tags[sig]; !ok {
+			t.Errorf("missing %s tag", sig)
+		}
+	}
+}
+
+func TestLinearCurveIsIdentity(t *testing.T) {
+	tags := readTags(t, Profile(ProPhotoRGBSpace, Linear))
+	if n := binary.BigEndian.Uint32(tags["rTRC"][8:]); n != 0 {
+		t.Errorf("linear TRC has %d entries, want 0", n)
+	}
+}
+
+func TestToneCurveRoundTrip(t *testing.T) {
+	for _, c := range []ToneCurve{BT709, SRGB, Linear, NewToneCurve(1/1.8, 0)} {
+		for i := range 101 {
+			v := float64(i) / 100
+			if got := c.Decode(c.Encode(v)); math.Abs(got-v) > 1e-6 {
+				t.Errorf("curve %v: Decode(Encode(%v)) = %v", c, v, got)
+			}
+		}
+	}
+
+	// LibRaw's default curve is BT.709: toe ends at 0.018 linear.
+	if math.Abs(BT709.linear-0.018) > 0.001 || math.Abs(BT709.offset-0.099) > 0.001 {
+		t.Errorf("BT709 knee = %v, offset = %v", BT709.linear, BT709.offset)
+	}
+}
+
+func TestTemperature(t *testing.T) {
+	for _, tc := range []struct {
+		kelvin, tint float64
+		want         Chromaticity
+	}{
+		{6504, 0, Chromaticity{0.3135, 0.3237}},
+		{2856, 0, Chromaticity{0.4476, 0.4074}},
+	} {
+		got := Temperature(tc.kelvin, tc.tint)
+		if math.Abs(got.X-tc.want.X) > 2e-3 || math.Abs(got.Y-tc.want.Y) > 2e-3 {
+			t.Errorf("Temperature(%v, %v) = %v, want %v", tc.kelvin, tc.tint, got, tc.want)
+		}
+	}
+
+	// a positive (magenta) tint compensates a greener illuminant
+	if Temperature(5000, 20).Y <= Temperature(5000, 0).Y {
+		t.Error("positive tint does not move the white point towards green")
+	}
+}
+
+func TestConvertTo(t *testing.T) {
+	for _, dst := range []ColorSpace{SRGBSpace, AdobeRGBSpace, ProPhotoRGBSpace, Rec2020Space} {
+		m := SRGBSpace.ConvertTo(dst)
+		// white stays white, whatever the white points
+		if w := mulVec(m, [3]float64{1, 1, 1}); math.Abs(w[0]-1)+math.Abs(w[1]-1)+math.Abs(w[2]-1) > 1e-3 {
+			t.Errorf("sRGB white in %s = %v", dst.Name, w)
+		}
+	}
+	if r := mulVec(SRGBSpace.ConvertTo(SRGBSpace), [3]float64{1, 0, 0}); math.Abs(r[0]-1)+math.Abs(r[1])+math.Abs(r[2]) > 1e-9 {
+		t.Errorf("sRGB to sRGB is not the identity: red = %v", r)
+	}
+}
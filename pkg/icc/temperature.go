@@ -0,0 +1,75 @@
+package icc
+
+import "math"
+
+// robertson is the table of isotemperature lines (reciprocal megakelvin,
+// u, v, slope) Camera Raw uses to convert between white balance settings
+// and chromaticities.
+var robertson = [...][4]float64{
+	{0, 0.18006, 0.26352, -0.24341},
+	{10, 0.18066, 0.26589, -0.25479},
+	{20, 0.18133, 0.26846, -0.26876},
+	{30, 0.18208, 0.27119, -0.28539},
+	{40, 0.18293, 0.27407, -0.30470},
+	{50, 0.18388, 0.27709, -0.32675},
+	{60, 0.18494, 0.28021, -0.35156},
+	{70, 0.18611, 0.28342, -0.37915},
+	{80, 0.18740, 0.28668, -0.40955},
+	{90, 0.18880, 0.28997, -0.44278},
+	{100, 0.19032, 0.29326, -0.47888},
+	{125, 0.19462, 0.30141, -0.58204},
+	{150, 0.19962, 0.30921, -0.70471},
+	{175, 0.20525, 0.31647, -0.84901},
+	{200, 0.21142, 0.32312, -1.0182},
+	{225, 0.21807, 0.32909, -1.2168},
+	{250, 0.22511, 0.33439, -1.4512},
+	{275, 0.23247, 0.33904, -1.7298},
+	{300, 0.24010, 0.34308, -2.0637},
+	{325, 0.24702, 0.34655, -2.4681},
+	{350, 0.25591, 0.34951, -2.9641},
+	{375, 0.26400, 0.35200, -3.5814},
+	{400, 0.27218, 0.35407, -4.3633},
+	{425, 0.28039, 0.35577, -5.3762},
+	{450, 0.28863, 0.35714, -6.7262},
+	{475, 0.29685, 0.35823, -8.5955},
+	{500, 0.30505, 0.35907, -11.324},
+	{525, 0.31320, 0.35968, -15.628},
+	{550, 0.32129, 0.36011, -23.325},
+	{575, 0.32931, 0.36038, -40.770},
+	{600, 0.33724, 0.36051, -116.45},
+}
+
+// tintScale converts Camera Raw tint units to distances in uv space.
+const tintScale = -3000.0
+
+// Temperature returns the white point of a Camera Raw style white balance
+// setting: a correlated color temperature in Kelvin and a tint offset
+// along the isotemperature line. A positive (magenta) tint corrects for
+// a greenish illuminant.
+func Temperature(kelvin, tint float64) Chromaticity {
+	r := 1e6 / kelvin
+	offset := tint / tintScale
+
+	for i := 1; i < len(robertson); i++ {
+		lo, hi := robertson[i-1], robertson[i]
+		if r >= hi[0] && i < len(robertson)-1 {
+			continue
+		}
+
+		f := (hi[0] - r) / (hi[0] - lo[0])
+		u := lo[1]*f + hi[1]*(1-f)
+		v := lo[2]*f + hi[2]*(1-f)
+
+		// interpolate the direction of the isotemperature lines
+		l1, l2 := math.Hypot(1, lo[3]), math.Hypot(1, hi[3])
+		du := f/l1 + (1-f)/l2
+		dv := lo[3]/l1*f + hi[3]/l2*(1-f)
+		l3 := math.Hypot(du, dv)
+		u += du / l3 * offset
+		v += dv / l3 * offset
+
+		d := u - 4*v + 2
+		return Chromaticity{1.5 * u / d, v / d}
+	}
+	return D65
+}
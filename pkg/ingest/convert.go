@@ -0,0 +1,80 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	libraw "github.com/stmtc233/go-libraw"
+	"github.com/stmtc233/go-libraw/pkg/export"
+)
+
+// convert renders a file with the configured options and writes the
+// image, the preview if enabled, and the metadata as <name>.json.
+func (in *Ingester) convert(ctx context.Context, path string) ([]string, error) {
+	p := libraw.NewProcessor(in.opts).WithContext(ctx)
+	base := in.outputBase(path)
+	var outputs []string
+	write := func(name string, data []byte) error {
+		if err := writeAtomic(name, data); err != nil {
+			return err
+		}
+		outputs = append(outputs, name)
+		return nil
+	}
+
+	rendered, err := p.Render(path)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	ext := ".tiff"
+	switch in.cfg.Format {
+	case "jpeg":
+		ext = ".jpg"
+		err = rendered.EncodeJPEG(&buf, in.cfg.JPEGQuality)
+	case "png":
+		ext = ".png"
+		err = rendered.EncodePNG(&buf)
+	default:
+		err = rendered.EncodeTIFF(&buf, nil)
+	}
+	if err == nil {
+		err = write(base+ext, buf.Bytes())
+	}
+	if err != nil {
+		return outputs, err
+	}
+	rendered = nil // let the full image go before decoding more
+
+	if in.cfg.Thumbnails {
+		preview, err := p.Preview(path, 0)
+		if err != nil {
+			return outputs, err
+		}
+		buf.Reset()
+		if err := export.EncodeJPEG(&buf, preview.Image, &preview.Metadata, in.cfg.JPEGQuality); err != nil {
+			return outputs, err
+		}
+		if err := write(base+".thumb.jpg", buf.Bytes()); err != nil {
+			return outputs, err
+		}
+	}
+
+	info, err := p.Identify(path, false)
+	if err != nil {
+		return outputs, err
+	}
+	data, err := json.MarshalIndent(struct {
+		Source  string   `json:"source"`
+		Outputs []string `json:"outputs"`
+		*libraw.FileInfo
+	}{path, outputs, info}, "", "  ")
+	if err != nil {
+		return outputs, err
+	}
+	if err := write(base+".json", data); err != nil {
+		return outputs, err
+	}
+	return outputs, nil
+}
@@ -0,0 +1,300 @@
+// Package ingest converts RAW files as they arrive in watched directories
+// ("hot folders"), as for tethered shooting. New files are noticed through
+// inotify on Linux and by polling elsewhere; a file is converted once its
+// size and modification time have stopped changing. Every handled file is
+// recorded in a state file, so a restarted Ingester only converts files
+// that are new or changed.
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	libraw "github.com/stmtc233/go-libraw"
+)
+
+// Config configures an Ingester. Dirs and Output are required.
+type Config struct {
+	Dirs   []string // directories watched, with their subdirectories; with several, their names must differ
+	Output string   // directory the outputs are written to, mirroring Dirs (below Output/<name> with several)
+
+	// Options are the processing options, e.g. from libraw.LookupPreset.
+	// Nil selects libraw.NewProcessorOptions.
+	Options *libraw.ProcessorOptions
+
+	Format      string // "tiff" (default), "jpeg" or "png"
+	JPEGQuality int    // 0 selects 90
+	Thumbnails  bool   // also write an upright preview, <name>.thumb.jpg
+
+	StateFile    string        // default Output/.ingest-state.json
+	SettleTime   time.Duration // how long a file must stay unchanged; 0 selects 2s
+	PollInterval time.Duration // how often pending files (and without inotify, the directories) are checked; 0 selects 1s
+	Poll         bool          // poll even where inotify is available
+	Workers      int           // files converted in parallel; 0 selects 1
+
+	// OnResult, if set, is called after each file, from one goroutine.
+	OnResult func(Result)
+}
+
+// Result reports the conversion of one file.
+type Result struct {
+	Path    string
+	Outputs []string
+	Err     error
+}
+
+// watcher delivers the paths of new or written files. An empty path asks
+// for a rescan of all directories.
+type watcher interface {
+	Events() <-chan string
+	Close() error
+}
+
+// Ingester converts the RAW files arriving in a set of directories.
+type Ingester struct {
+	cfg  Config
+	dirs []string // absolute
+	opts libraw.ProcessorOptions
+
+	// process converts one file; replaced in tests.
+	process func(ctx context.Context, path string) ([]string, error)
+}
+
+// New checks cfg and returns an Ingester for it.
+func New(cfg Config) (*Ingester, error) {
+	if len(cfg.Dirs) == 0 || cfg.Output == "" {
+		return nil, fmt.Errorf("ingest: watched directories and an output directory are required")
+	}
+	in := &Ingester{cfg: cfg, opts: libraw.NewProcessorOptions()}
+	for _, dir := range cfg.Dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		if st, err := os.Stat(abs); err != nil {
+			return nil, err
+		} else if !st.IsDir() {
+			return nil, fmt.Errorf("ingest: %s is not a directory", dir)
+		}
+		in.dirs = append(in.dirs, abs)
+	}
+	// with several directories, outputs go below each one's name
+	if len(in.dirs) > 1 {
+		names := map[string]string{}
+		for _, dir := range in.dirs {
+			name := filepath.Base(dir)
+			if other, ok := names[name]; ok {
+				return nil, fmt.Errorf("ingest: %s and %s would share the output directory %s", other, dir, filepath.Join(cfg.Output, name))
+			}
+			names[name] = dir
+		}
+	}
+
+	if cfg.Options != nil {
+		in.opts = *cfg.Options
+	}
+	if err := in.opts.Validate(); err != nil {
+		return nil, err
+	}
+	switch in.cfg.Format {
+	case "":
+		in.cfg.Format = "tiff"
+	case "tiff", "jpeg", "png":
+	default:
+		return nil, fmt.Errorf("ingest: unknown format %q", cfg.Format)
+	}
+	if in.cfg.JPEGQuality <= 0 {
+		in.cfg.JPEGQuality = 90
+	}
+	if in.cfg.StateFile == "" {
+		in.cfg.StateFile = filepath.Join(cfg.Output, ".ingest-state.json")
+	}
+	if in.cfg.SettleTime <= 0 {
+		in.cfg.SettleTime = 2 * time.Second
+	}
+	if in.cfg.PollInterval <= 0 {
+		in.cfg.PollInterval = time.Second
+	}
+	if in.cfg.Workers <= 0 {
+		in.cfg.Workers = 1
+	}
+	in.process = in.convert
+	return in, nil
+}
+
+// candidate is a file waiting to settle.
+type candidate struct {
+	size  int64
+	mod   time.Time
+	since time.Time // when size and mod were first seen
+}
+
+// Run watches the directories until ctx is done. Files present at start
+// that are not in the state file are converted too. Conversions in flight
+// when ctx ends are abandoned and redone by the next Run.
+func (in *Ingester) Run(ctx context.Context) error {
+	st, err := loadState(in.cfg.StateFile)
+	if err != nil {
+		return fmt.Errorf("ingest: read state: %w", err)
+	}
+
+	var events <-chan string
+	if !in.cfg.Poll {
+		if w, err := newWatcher(in.dirs); err == nil {
+			defer w.Close()
+			events = w.Events()
+		}
+	}
+
+	pending := map[string]*candidate{}
+	queued := map[string]bool{} // in ready or being converted
+	var ready []string
+
+	add := func(path string, now time.Time) {
+		if !libraw.IsRawFile(path) || queued[path] || pending[path] != nil {
+			return
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			return
+		}
+		if rec, ok := st[path]; ok && rec.matches(info) {
+			return
+		}
+		pending[path] = &candidate{size: info.Size(), mod: info.ModTime(), since: now}
+	}
+	scan := func() {
+		now := time.Now()
+		for _, dir := range in.dirs {
+			filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					add(path, now)
+				}
+				return nil
+			})
+		}
+	}
+	settle := func() {
+		now := time.Now()
+		for path, c := range pending {
+			info, err := os.Stat(path)
+			switch {
+			case err != nil:
+				delete(pending, path)
+			case info.Size() != c.size || !info.ModTime().Equal(c.mod):
+				*c = candidate{size: info.Size(), mod: info.ModTime(), since: now}
+			case info.Size() > 0 && now.Sub(c.since) >= in.cfg.SettleTime:
+				delete(pending, path)
+				queued[path] = true
+				ready = append(ready, path)
+			}
+		}
+	}
+
+	jobs := make(chan string)
+	results := make(chan Result)
+	var wg sync.WaitGroup
+	for range in.cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				outputs, err := in.process(ctx, path)
+				results <- Result{Path: path, Outputs: outputs, Err: err}
+			}
+		}()
+	}
+
+	record := func(r Result) error {
+		delete(queued, r.Path)
+		if ctx.Err() != nil {
+			return nil // abandoned, not done
+		}
+		info, err := os.Stat(r.Path)
+		if err == nil {
+			rec := Record{Size: info.Size(), ModTime: info.ModTime(), Done: time.Now(), Outputs: r.Outputs}
+			if r.Err != nil {
+				rec.Error = r.Err.Error()
+			}
+			st[r.Path] = rec
+			err = st.save(in.cfg.StateFile)
+		}
+		if in.cfg.OnResult != nil {
+			in.cfg.OnResult(r)
+		}
+		if err != nil {
+			return fmt.Errorf("ingest: save state: %w", err)
+		}
+		return nil
+	}
+
+	scan()
+	ticker := time.NewTicker(in.cfg.PollInterval)
+	defer ticker.Stop()
+
+	var runErr error
+loop:
+	for {
+		var send chan string
+		var next string
+		if len(ready) > 0 {
+			send, next = jobs, ready[0]
+		}
+		select {
+		case <-ctx.Done():
+			break loop
+		case path, ok := <-events:
+			switch {
+			case !ok:
+				events = nil // watcher failed, poll from now on
+			case path == "":
+				scan()
+			default:
+				add(path, time.Now())
+			}
+		case <-ticker.C:
+			if events == nil {
+				scan()
+			}
+			settle()
+		case send <- next:
+			ready = ready[1:]
+		case r := <-results:
+			if runErr = record(r); runErr != nil {
+				break loop
+			}
+		}
+	}
+
+	close(jobs)
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	for r := range results {
+		if err := record(r); err != nil && runErr == nil {
+			runErr = err
+		}
+	}
+	return runErr
+}
+
+// outputBase returns the output path of a file without extension.
+func (in *Ingester) outputBase(path string) string {
+	for _, dir := range in.dirs {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+		if len(in.dirs) > 1 {
+			rel = filepath.Join(filepath.Base(dir), rel)
+		}
+		return filepath.Join(in.cfg.Output, rel[:len(rel)-len(filepath.Ext(rel))])
+	}
+	return filepath.Join(in.cfg.Output, filepath.Base(path))
+}
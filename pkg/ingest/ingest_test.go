@@ -0,0 +1,238 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "state.json")
+	s, err := loadState(path)
+	if err != nil || len(s) != 0 {
+		t.Fatalf("missing state file: %v, %v", s, err)
+	}
+	mod := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s["/in/a.nef"] = Record{Size: 10, ModTime: mod, Outputs: []string{"/out/a.tiff"}}
+	s["/in/b.nef"] = Record{Size: 20, ModTime: mod, Error: "bad file"}
+	if err := s.save(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["/in/b.nef"].Error != "bad file" || !got["/in/a.nef"].ModTime.Equal(mod) {
+		t.Errorf("loaded %+v", got)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+	if st, err := os.Stat(path); err != nil {
+		t.Error(err)
+	} else if st.Mode().Perm() != 0o644 {
+		t.Errorf("state file mode %v", st.Mode())
+	}
+}
+
+func TestOutputBase(t *testing.T) {
+	in1, _ := New(Config{Dirs: []string{t.TempDir()}, Output: "/out"})
+	if got := in1.outputBase(filepath.Join(in1.dirs[0], "day1", "IMG_1.CR2")); got != filepath.FromSlash("/out/day1/IMG_1") {
+		t.Errorf("one dir: %s", got)
+	}
+
+	a, b := filepath.Join(t.TempDir(), "a"), filepath.Join(t.TempDir(), "b")
+	os.Mkdir(a, 0o755)
+	os.Mkdir(b, 0o755)
+	in2, _ := New(Config{Dirs: []string{a, b}, Output: "/out"})
+	if got := in2.outputBase(filepath.Join(b, "IMG_1.CR2")); got != filepath.FromSlash("/out/b/IMG_1") {
+		t.Errorf("two dirs: %s", got)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	dir := t.TempDir()
+	studio1, studio2 := filepath.Join(dir, "studio1", "tether"), filepath.Join(dir, "studio2", "tether")
+	os.MkdirAll(studio1, 0o755)
+	os.MkdirAll(studio2, 0o755)
+	for _, cfg := range []Config{
+		{Dirs: []string{studio1, studio2}, Output: dir}, // both would write to Output/tether
+		{Output: dir},
+		{Dirs: []string{dir}},
+		{Dirs: []string{filepath.Join(dir, "missing")}, Output: dir},
+		{Dirs: []string{dir}, Output: dir, Format: "bmp"},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) succeeded", cfg)
+		}
+	}
+}
+
+// run starts an Ingester with a fake conversion and returns a channel of
+// the results and a function stopping it.
+func run(t *testing.T, cfg Config, fail map[string]bool) (<-chan Result, func()) {
+	t.Helper()
+	results := make(chan Result, 16)
+	cfg.SettleTime = 50 * time.Millisecond
+	cfg.PollInterval = 10 * time.Millisecond
+	cfg.OnResult = func(r Result) { results <- r }
+	in, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.process = func(ctx context.Context, path string) ([]string, error) {
+		if fail[filepath.Base(path)] {
+			return nil, errors.New("cannot decode")
+		}
+		out := in.outputBase(path) + ".tiff"
+		return []string{out}, writeAtomic(out, []byte("x"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := in.Run(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+	return results, func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func next(t *testing.T, results <-chan Result) Result {
+	t.Helper()
+	select {
+	case r := <-results:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("no result")
+		return Result{}
+	}
+}
+
+func quiet(t *testing.T, results <-chan Result) {
+	t.Helper()
+	select {
+	case r := <-results:
+		t.Errorf("unexpected result %+v", r)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func testRun(t *testing.T, poll bool) {
+	in, out := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(in, "existing.nef"), []byte("raw"), 0o644)
+	os.WriteFile(filepath.Join(in, "notes.txt"), []byte("text"), 0o644)
+	os.WriteFile(filepath.Join(in, "broken.nef"), []byte("raw"), 0o644)
+	cfg := Config{Dirs: []string{in}, Output: out, Poll: poll}
+	fail := map[string]bool{"broken.nef": true}
+
+	results, stop := run(t, cfg, fail)
+	seen := map[string]Result{}
+	for range 2 {
+		r := next(t, results)
+		seen[filepath.Base(r.Path)] = r
+	}
+	if seen["existing.nef"].Err != nil || seen["broken.nef"].Err == nil {
+		t.Errorf("initial results %+v", seen)
+	}
+
+	// files appearing later, including in new directories
+	os.MkdirAll(filepath.Join(in, "day2"), 0o755)
+	os.WriteFile(filepath.Join(in, "day2", "new.cr2"), []byte("raw"), 0o644)
+	if r := next(t, results); r.Err != nil || filepath.Base(r.Path) != "new.cr2" {
+		t.Errorf("new file: %+v", r)
+	}
+	if _, err := os.Stat(filepath.Join(out, "day2", "new.tiff")); err != nil {
+		t.Error(err)
+	}
+	quiet(t, results)
+	stop()
+
+	// a restart converts nothing that is unchanged, failures included
+	results, stop = run(t, cfg, fail)
+	quiet(t, results)
+	stop()
+
+	// but a replaced file is converted again
+	os.WriteFile(filepath.Join(in, "existing.nef"), []byte("raw, again"), 0o644)
+	results, stop = run(t, cfg, fail)
+	if r := next(t, results); filepath.Base(r.Path) != "existing.nef" {
+		t.Errorf("changed file: %+v", r)
+	}
+	quiet(t, results)
+	stop()
+}
+
+func TestRunPolling(t *testing.T)  { testRun(t, true) }
+func TestRunWatching(t *testing.T) { testRun(t, false) }
+
+func TestRunWaitsForWrites(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	results, stop := run(t, Config{Dirs: []string{in}, Output: out}, nil)
+	defer stop()
+
+	f, err := os.Create(filepath.Join(in, "slow.arw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		f.Write([]byte("chunk"))
+		time.Sleep(30 * time.Millisecond)
+	}
+	f.Close()
+
+	next(t, results)
+	state, err := loadState(filepath.Join(out, ".ingest-state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := state[filepath.Join(in, "slow.arw")]; rec.Size != 25 {
+		t.Errorf("converted before the write finished: %+v", rec)
+	}
+}
+
+func TestRunQueuesOnce(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	for _, name := range []string{"a.CR2", "b.CR2", "c.CR2"} {
+		os.WriteFile(filepath.Join(in, name), []byte("raw"), 0o644)
+	}
+	ing, err := New(Config{
+		Dirs: []string{in}, Output: out, Poll: true,
+		SettleTime: 10 * time.Millisecond, PollInterval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	counts := map[string]int{}
+	ing.process = func(ctx context.Context, path string) ([]string, error) {
+		mu.Lock()
+		counts[filepath.Base(path)]++
+		mu.Unlock()
+		time.Sleep(100 * time.Millisecond) // rescans happen meanwhile
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ing.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, name := range []string{"a.CR2", "b.CR2", "c.CR2"} {
+		if counts[name] != 1 {
+			t.Errorf("%s converted %d times", name, counts[name])
+		}
+	}
+}
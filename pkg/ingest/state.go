@@ -0,0 +1,78 @@
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Record is the state kept for a file that was converted or failed.
+type Record struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Done    time.Time `json:"done"`
+
+	Outputs []string `json:"outputs,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// matches reports whether the record describes the file as it is now.
+func (r Record) matches(info fs.FileInfo) bool {
+	return r.Size == info.Size() && r.ModTime.Equal(info.ModTime())
+}
+
+// state maps the absolute path of every handled file to its record. A
+// file is handled again only when its size or modification time changes.
+type state map[string]Record
+
+func loadState(path string) (state, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	s := state{}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// save writes the state through a temporary file, so a crash leaves
+// either the old or the new state behind.
+func (s state) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, data)
+}
+
+// writeAtomic replaces path with data.
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0o644) // CreateTemp makes it private
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+//go:build linux
+
+package ingest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const watchMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO
+
+// inotifyWatcher reports files created, written or moved into the watched
+// directories and their subdirectories.
+type inotifyWatcher struct {
+	fd     int      // kept apart: File.Fd would make f blocking
+	f      *os.File // non-blocking, so the runtime poller serves Read and Close interrupts it
+	events chan string
+	done   chan struct{}
+
+	mu   sync.Mutex
+	dirs map[int32]string // watch descriptor -> directory
+}
+
+func newWatcher(dirs []string) (watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &inotifyWatcher{
+		fd:     fd,
+		f:      os.NewFile(uintptr(fd), "inotify"),
+		events: make(chan string, 256),
+		done:   make(chan struct{}),
+		dirs:   map[int32]string{},
+	}
+	for _, dir := range dirs {
+		if err := w.addTree(dir); err != nil {
+			w.f.Close()
+			return nil, err
+		}
+	}
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *inotifyWatcher) Close() error {
+	close(w.done)
+	return w.f.Close()
+}
+
+// send passes an event on unless the watcher was closed.
+func (w *inotifyWatcher) send(path string) bool {
+	select {
+	case w.events <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// addTree watches dir and every directory below it.
+func (w *inotifyWatcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, path, watchMask)
+		if err != nil {
+			return os.NewSyscallError("inotify_add_watch "+path, err)
+		}
+		w.mu.Lock()
+		w.dirs[int32(wd)] = path
+		w.mu.Unlock()
+		return nil
+	})
+}
+
+func (w *inotifyWatcher) read() {
+	defer close(w.events)
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameBytes := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)]
+			off += syscall.SizeofInotifyEvent + int(ev.Len)
+
+			if ev.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				if !w.send("") { // events were lost, rescan
+					return
+				}
+				continue
+			}
+			w.mu.Lock()
+			dir, ok := w.dirs[ev.Wd]
+			if ev.Mask&syscall.IN_IGNORED != 0 {
+				delete(w.dirs, ev.Wd)
+			}
+			w.mu.Unlock()
+			if !ok || ev.Len == 0 {
+				continue
+			}
+
+			path := filepath.Join(dir, string(bytes.TrimRight(nameBytes, "\x00")))
+			if ev.Mask&syscall.IN_ISDIR != 0 {
+				// files may arrive before the watch is in place
+				if w.addTree(path) == nil && !w.send("") {
+					return
+				}
+				continue
+			}
+			if !w.send(path) {
+				return
+			}
+		}
+	}
+}
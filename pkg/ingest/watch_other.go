@@ -0,0 +1,11 @@
+//go:build !linux
+
+package ingest
+
+import "errors"
+
+// newWatcher is only implemented with inotify; elsewhere the directories
+// are polled.
+func newWatcher(dirs []string) (watcher, error) {
+	return nil, errors.New("file system notifications are not supported on this platform")
+}
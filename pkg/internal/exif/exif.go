@@ -0,0 +1,167 @@
+// Package exif fills TIFF IFDs with the camera description and EXIF tags
+// of a RAW file. It is shared by the TIFF, DNG and JPEG writers.
+package exif
+
+import (
+	"math"
+
+	"github.com/stmtc233/go-libraw/pkg/internal/tiffio"
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// TIFF/EXIF tag numbers used when describing an image.
+const (
+	TagImageDescription = 270
+	TagMake             = 271
+	TagModel            = 272
+	TagOrientation      = 274
+	TagSoftware         = 305
+	TagDateTime         = 306
+	TagArtist           = 315
+
+	TagExposureTime          = 33434
+	TagFNumber               = 33437
+	TagISOSpeedRatings       = 34855
+	TagExifVersion           = 36864
+	TagDateTimeOriginal      = 36867
+	TagDateTimeDigitized     = 36868
+	TagFocalLength           = 37386
+	TagFocalLengthIn35mmFilm = 41989
+	TagBodySerialNumber      = 42033
+	TagLensSpecification     = 42034
+	TagLensMake              = 42035
+	TagLensModel             = 42036
+	TagLensSerialNumber      = 42037
+
+	TagGPSVersionID    = 0
+	TagGPSLatitudeRef  = 1
+	TagGPSLatitude     = 2
+	TagGPSLongitudeRef = 3
+	TagGPSLongitude    = 4
+	TagGPSAltitudeRef  = 5
+	TagGPSAltitude     = 6
+	TagGPSTimeStamp    = 7
+	TagGPSStatus       = 9
+)
+
+const DateLayout = "2006:01:02 15:04:05"
+
+// AddTags adds the camera description to ifd0 and attaches an EXIF
+// sub-IFD with the capture time, body serial number, exposure and lens,
+// plus a GPS IFD when the file has a position. Orientation is left to the
+// caller, as it depends on whether the pixels have already been rotated.
+func AddTags(ifd0 *tiffio.IFD, meta *metadata.ImgMetadata) {
+	if meta == nil {
+		return
+	}
+
+	ifd0.ASCII(TagMake, meta.IData.Make)
+	ifd0.ASCII(TagModel, meta.IData.Model)
+	ifd0.ASCII(TagSoftware, meta.IData.Software)
+	ifd0.ASCII(TagImageDescription, meta.Other.Description)
+	ifd0.ASCII(TagArtist, meta.Other.Artist)
+
+	exif := &tiffio.IFD{}
+	exif.Undefined(TagExifVersion, []byte("0230"))
+	if meta.CaptureTimestamp != 0 { // without one CaptureDate is the epoch
+		date := meta.CaptureDate.Format(DateLayout)
+		ifd0.ASCII(TagDateTime, date)
+		exif.ASCII(TagDateTimeOriginal, date)
+		exif.ASCII(TagDateTimeDigitized, date)
+	}
+	exif.ASCII(TagBodySerialNumber, meta.ShootingInfo.BodySerial)
+	addExposure(exif, &meta.Other)
+	addLens(exif, &meta.Lens)
+	ifd0.Sub(tiffio.TagExifIFD, exif)
+
+	if gps := &meta.Other.GPS; gps.Parsed {
+		ifd0.Sub(tiffio.TagGPSIFD, gpsIFD(gps))
+	}
+}
+
+func addExposure(exif *tiffio.IFD, other *metadata.ImgOther) {
+	if other.Shutter > 0 {
+		exif.Rational(TagExposureTime, exposureTime(other.Shutter))
+	}
+	if other.Aperture > 0 {
+		exif.Rational(TagFNumber, rational(other.Aperture, 10))
+	}
+	if other.ISOSpeed > 0 {
+		exif.Short(TagISOSpeedRatings, uint16(min(other.ISOSpeed+0.5, math.MaxUint16)))
+	}
+	if other.FocalLength > 0 {
+		exif.Rational(TagFocalLength, rational(other.FocalLength, 10))
+	}
+}
+
+func addLens(exif *tiffio.IFD, lens *metadata.LensInfo) {
+	exif.ASCII(TagLensMake, lens.Make)
+	exif.ASCII(TagLensModel, lens.Model)
+	exif.ASCII(TagLensSerialNumber, lens.Serial)
+	if lens.FocalLengthIn35mm > 0 {
+		exif.Short(TagFocalLengthIn35mmFilm, lens.FocalLengthIn35mm)
+	}
+	if lens.MinFocal > 0 && lens.MaxFocal > 0 {
+		// unknown apertures are written as 0/0, as the spec allows
+		ap := func(f float32) [2]uint32 {
+			if f > 0 {
+				return rational(f, 10)
+			}
+			return [2]uint32{}
+		}
+		exif.Rational(TagLensSpecification,
+			rational(lens.MinFocal, 10), rational(lens.MaxFocal, 10),
+			ap(lens.MaxAp4MinFocal), ap(lens.MaxAp4MaxFocal))
+	}
+}
+
+func gpsIFD(gps *metadata.GPSInfo) *tiffio.IFD {
+	ifd := &tiffio.IFD{}
+	ifd.Byte(TagGPSVersionID, 2, 3, 0, 0)
+	if gps.LatitudeRef != 0 {
+		ifd.ASCII(TagGPSLatitudeRef, string(gps.LatitudeRef))
+		ifd.Rational(TagGPSLatitude, dms(gps.Latitude)...)
+	}
+	if gps.LongitudeRef != 0 {
+		ifd.ASCII(TagGPSLongitudeRef, string(gps.LongitudeRef))
+		ifd.Rational(TagGPSLongitude, dms(gps.Longitude)...)
+	}
+	if gps.Altitude != 0 {
+		ifd.Byte(TagGPSAltitudeRef, gps.AltitudeRef)
+		ifd.Rational(TagGPSAltitude, rational(gps.Altitude, 100))
+	}
+	if gps.Timestamp != [3]float32{} {
+		ifd.Rational(TagGPSTimeStamp, dms(gps.Timestamp)...)
+	}
+	if gps.Status != 0 {
+		ifd.ASCII(TagGPSStatus, string(gps.Status))
+	}
+	return ifd
+}
+
+// exposureTime writes short exposures as 1/n seconds, the way cameras do.
+func exposureTime(s float32) [2]uint32 {
+	if s < 1 {
+		if n := math.Round(1 / float64(s)); math.Abs(1/n-float64(s)) < 1e-3*float64(s) {
+			return [2]uint32{1, uint32(n)}
+		}
+		return rational(s, 100000)
+	}
+	return rational(s, 10)
+}
+
+func rational(v float32, denom uint32) [2]uint32 {
+	return [2]uint32{uint32(math.Round(math.Abs(float64(v)) * float64(denom))), denom}
+}
+
+// dms writes degrees (or hours) and minutes as whole numbers when they
+// are, which is what most readers expect.
+func dms(v [3]float32) [][2]uint32 {
+	whole := func(f float32) [2]uint32 {
+		if f == float32(math.Trunc(float64(f))) {
+			return rational(f, 1)
+		}
+		return rational(f, 1000000)
+	}
+	return [][2]uint32{whole(v[0]), whole(v[1]), rational(v[2], 1000)}
+}
@@ -0,0 +1,280 @@
+// Package tiffio lays out little-endian TIFF files from a tree of IFDs.
+// It knows nothing about images; callers add the tags and strip data.
+package tiffio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"slices"
+)
+
+// Type is a TIFF field type.
+type Type uint16
+
+const (
+	Byte      Type = 1
+	ASCII     Type = 2
+	Short     Type = 3
+	Long      Type = 4
+	Rational  Type = 5
+	SByte     Type = 6
+	Undefined Type = 7
+	SShort    Type = 8
+	SLong     Type = 9
+	SRational Type = 10
+	Float     Type = 11
+	Double    Type = 12
+)
+
+var typeSize = map[Type]int{
+	Byte: 1, ASCII: 1, Short: 2, Long: 4, Rational: 8, SByte: 1,
+	Undefined: 1, SShort: 2, SLong: 4, SRational: 8, Float: 4, Double: 8,
+}
+
+// Tags that the writer fills in itself.
+const (
+	TagSubIFDs         = 330
+	TagStripOffsets    = 273
+	TagStripByteCounts = 279
+	TagTileOffsets     = 324
+	TagTileByteCounts  = 325
+	TagExifIFD         = 34665
+	TagGPSIFD          = 34853
+)
+
+var order = binary.LittleEndian
+
+type entry struct {
+	tag   uint16
+	typ   Type
+	count uint32
+	data  []byte
+}
+
+// IFD is a single image file directory. Pointer tags (EXIF, GPS, SubIFDs)
+// and strip or tile offsets are resolved when the file is written.
+type IFD struct {
+	entries []entry
+	subs    map[uint16][]*IFD
+
+	// Strips holds the (already compressed) image data segments. If Tiled
+	// is set they are written as tiles rather than strips.
+	Strips [][]byte
+	Tiled  bool
+}
+
+func (d *IFD) add(tag uint16, typ Type, count int, data []byte) {
+	d.entries = slices.DeleteFunc(d.entries, func(e entry) bool { return e.tag == tag })
+	d.entries = append(d.entries, entry{tag, typ, uint32(count), data})
+}
+
+// Has reports whether the tag has been set.
+func (d *IFD) Has(tag uint16) bool {
+	return slices.ContainsFunc(d.entries, func(e entry) bool { return e.tag == tag })
+}
+
+// Len reports the number of tags set, not counting generated ones.
+func (d *IFD) Len() int {
+	return len(d.entries)
+}
+
+func (d *IFD) Byte(tag uint16, v ...uint8) {
+	d.add(tag, Byte, len(v), slices.Clone(v))
+}
+
+func (d *IFD) Undefined(tag uint16, v []byte) {
+	d.add(tag, Undefined, len(v), slices.Clone(v))
+}
+
+// ASCII stores s as a NUL terminated string. Empty strings are skipped.
+func (d *IFD) ASCII(tag uint16, s string) {
+	if s == "" {
+		return
+	}
+	d.add(tag, ASCII, len(s)+1, append([]byte(s), 0))
+}
+
+func (d *IFD) Short(tag uint16, v ...uint16) {
+	b := make([]byte, 2*len(v))
+	for i, x := range v {
+		order.PutUint16(b[2*i:], x)
+	}
+	d.add(tag, Short, len(v), b)
+}
+
+func (d *IFD) SShort(tag uint16, v ...int16) {
+	b := make([]byte, 2*len(v))
+	for i, x := range v {
+		order.PutUint16(b[2*i:], uint16(x))
+	}
+	d.add(tag, SShort, len(v), b)
+}
+
+func (d *IFD) Long(tag uint16, v ...uint32) {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		order.PutUint32(b[4*i:], x)
+	}
+	d.add(tag, Long, len(v), b)
+}
+
+func (d *IFD) SLong(tag uint16, v ...int32) {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		order.PutUint32(b[4*i:], uint32(x))
+	}
+	d.add(tag, SLong, len(v), b)
+}
+
+func (d *IFD) Float(tag uint16, v ...float32) {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		order.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+	d.add(tag, Float, len(v), b)
+}
+
+// Rational stores each value as a numerator/denominator pair.
+func (d *IFD) Rational(tag uint16, v ...[2]uint32) {
+	b := make([]byte, 8*len(v))
+	for i, x := range v {
+		order.PutUint32(b[8*i:], x[0])
+		order.PutUint32(b[8*i+4:], x[1])
+	}
+	d.add(tag, Rational, len(v), b)
+}
+
+func (d *IFD) SRational(tag uint16, v ...[2]int32) {
+	b := make([]byte, 8*len(v))
+	for i, x := range v {
+		order.PutUint32(b[8*i:], uint32(x[0]))
+		order.PutUint32(b[8*i+4:], uint32(x[1]))
+	}
+	d.add(tag, SRational, len(v), b)
+}
+
+// Sub attaches child IFDs under a pointer tag such as TagExifIFD.
+func (d *IFD) Sub(tag uint16, ifds ...*IFD) {
+	if d.subs == nil {
+		d.subs = map[uint16][]*IFD{}
+	}
+	d.subs[tag] = ifds
+}
+
+// Encode serializes the IFD chain as a complete TIFF file. The first IFD
+// is IFD0; the others are linked through the next-IFD pointer.
+func Encode(w io.Writer, chain ...*IFD) error {
+	var buf bytes.Buffer
+	buf.WriteString("II")
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(0)) // patched below
+
+	next := 4
+	for _, d := range chain {
+		off := d.write(&buf)
+		order.PutUint32(buf.Bytes()[next:], uint32(off))
+		next = off + 2 + 12*(len(d.entries)+d.generated())
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// EncodeBody serializes a single IFD tree the way it appears inside an
+// EXIF APP1 segment: a TIFF header followed by IFD0, with no trailing
+// chain.
+func EncodeBody(d *IFD) []byte {
+	var buf bytes.Buffer
+	Encode(&buf, d)
+	return buf.Bytes()
+}
+
+func (d *IFD) generated() int {
+	n := len(d.subs)
+	if d.Strips != nil {
+		n += 2
+	}
+	return n
+}
+
+// write places the IFD, its out-of-line values, its children and its
+// strips at the end of buf and returns the IFD offset.
+func (d *IFD) write(buf *bytes.Buffer) int {
+	pad(buf)
+	entries := slices.Clone(d.entries)
+
+	offsetTag, countTag := uint16(TagStripOffsets), uint16(TagStripByteCounts)
+	if d.Tiled {
+		offsetTag, countTag = TagTileOffsets, TagTileByteCounts
+	}
+	if d.Strips != nil {
+		counts := make([]byte, 4*len(d.Strips))
+		for i, s := range d.Strips {
+			order.PutUint32(counts[4*i:], uint32(len(s)))
+		}
+		entries = append(entries,
+			entry{offsetTag, Long, uint32(len(d.Strips)), make([]byte, 4*len(d.Strips))},
+			entry{countTag, Long, uint32(len(d.Strips)), counts},
+		)
+	}
+	for tag, ifds := range d.subs {
+		entries = append(entries, entry{tag, Long, uint32(len(ifds)), make([]byte, 4*len(ifds))})
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return int(a.tag) - int(b.tag) })
+
+	start := buf.Len()
+	dataOff := start + 2 + 12*len(entries) + 4
+	pos := map[uint16]int{} // where each entry's value bytes live
+
+	binary.Write(buf, order, uint16(len(entries)))
+	var extra bytes.Buffer
+	for _, e := range entries {
+		binary.Write(buf, order, e.tag)
+		binary.Write(buf, order, e.typ)
+		binary.Write(buf, order, e.count)
+		if len(e.data) <= 4 {
+			pos[e.tag] = buf.Len()
+			var v [4]byte
+			copy(v[:], e.data)
+			buf.Write(v[:])
+			continue
+		}
+		off := dataOff + extra.Len()
+		pos[e.tag] = off
+		binary.Write(buf, order, uint32(off))
+		extra.Write(e.data)
+		pad(&extra)
+	}
+	binary.Write(buf, order, uint32(0)) // next IFD, patched by Encode
+	buf.Write(extra.Bytes())
+
+	// Children are written depth first; their offsets are patched into
+	// the placeholders reserved above.
+	tags := make([]uint16, 0, len(d.subs))
+	for tag := range d.subs {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	for _, tag := range tags {
+		for i, sub := range d.subs[tag] {
+			off := sub.write(buf)
+			order.PutUint32(buf.Bytes()[pos[tag]+4*i:], uint32(off))
+		}
+	}
+
+	for i, s := range d.Strips {
+		pad(buf)
+		order.PutUint32(buf.Bytes()[pos[offsetTag]+4*i:], uint32(buf.Len()))
+		buf.Write(s)
+	}
+
+	return start
+}
+
+func pad(buf *bytes.Buffer) {
+	if buf.Len()%2 != 0 {
+		buf.WriteByte(0)
+	}
+}
@@ -0,0 +1,50 @@
+package metadata
+
+// ColorData holds the color related values LibRaw reads from a RAW file
+// (a subset of libraw_colordata_t), as found right after unpacking.
+// Per channel arrays are indexed like LibRawIData.ColorDescription.
+type ColorData struct {
+	Black        uint32     `json:"black"`                   // black level common to all channels
+	ChannelBlack [4]uint32  `json:"channel_black"`           // additional black level per channel
+	BlackPattern [][]uint32 `json:"black_pattern,omitempty"` // additional black level per position of a repeating pattern, if any
+	Maximum      uint32     `json:"maximum"`                 // white level
+	DataMaximum  uint32     `json:"data_maximum"`            // largest value actually found in the data, if computed
+
+	CamMul [4]float32    `json:"cam_mul"` // as shot white balance multipliers
+	PreMul [4]float32    `json:"pre_mul"` // daylight white balance multipliers
+	CamXYZ [4][3]float32 `json:"cam_xyz"` // XYZ (D65) to camera matrix, one row per channel
+	RGBCam [3][4]float32 `json:"rgb_cam"` // camera to sRGB matrix
+
+	FlashUsed float32 `json:"flash_used"`
+	RawBps    uint    `json:"raw_bps"` // bits per sample of the raw data
+}
+
+// BlackAt returns the total black level of the pixel at row, col
+// (relative to the visible area) whose channel is color.
+func (c *ColorData) BlackAt(row, col, color int) uint32 {
+	black := c.Black
+	if color >= 0 && color < 4 {
+		black += c.ChannelBlack[color]
+	}
+	if len(c.BlackPattern) > 0 && len(c.BlackPattern[0]) > 0 {
+		pat := c.BlackPattern[row%len(c.BlackPattern)]
+		black += pat[col%len(pat)]
+	}
+	return black
+}
+
+// AsShotNeutral returns the camera neutral for the as shot white balance,
+// normalized so the second channel is 1, as stored in DNG AsShotNeutral.
+// It returns false when the file has no as shot white balance.
+func (c *ColorData) AsShotNeutral() ([4]float64, bool) {
+	var neutral [4]float64
+	if c.CamMul[1] <= 0 {
+		return neutral, false
+	}
+	for i, m := range c.CamMul {
+		if m > 0 {
+			neutral[i] = float64(c.CamMul[1]) / float64(m)
+		}
+	}
+	return neutral, true
+}
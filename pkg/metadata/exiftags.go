@@ -0,0 +1,153 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// TIFF field types of ExifTag.Type.
+const (
+	TypeByte      = 1
+	TypeASCII     = 2
+	TypeShort     = 3
+	TypeLong      = 4
+	TypeRational  = 5
+	TypeSByte     = 6
+	TypeUndefined = 7
+	TypeSShort    = 8
+	TypeSLong     = 9
+	TypeSRational = 10
+	TypeFloat     = 11
+	TypeDouble    = 12
+	TypeIFD       = 13
+)
+
+// ExifTag is a TIFF, EXIF or makernote tag as LibRaw's parser saw it.
+type ExifTag struct {
+	// IFD is the upper half of the tag number LibRaw reports: 0 for tags
+	// of the EXIF IFD and a LibRaw assigned code for other directories
+	// (TIFF IFDs, GPS, makernotes).
+	IFD       uint16 `json:"ifd"`
+	ID        uint16 `json:"id"`
+	Type      uint16 `json:"type"`
+	Count     uint32 `json:"count"`
+	BigEndian bool   `json:"big_endian"`
+	// Value holds the raw value bytes in the byte order of the file. It is
+	// nil when the value is larger than MaxExifValueSize.
+	Value []byte `json:"value,omitempty"`
+}
+
+// MaxExifValueSize limits the value bytes kept per tag, so embedded
+// previews and makernote blobs do not end up in the metadata.
+const MaxExifValueSize = 64 << 10
+
+// ExifTags is the list of tags collected while opening a file, in the
+// order LibRaw parsed them.
+type ExifTags []ExifTag
+
+// Find returns the first tag with the given IFD code and ID.
+func (tags ExifTags) Find(ifd, id uint16) (ExifTag, bool) {
+	for _, t := range tags {
+		if t.IFD == ifd && t.ID == id {
+			return t, true
+		}
+	}
+	return ExifTag{}, false
+}
+
+// ByteOrder returns the byte order of Value.
+func (t ExifTag) ByteOrder() binary.ByteOrder {
+	if t.BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// String returns an ASCII value without its NUL terminator.
+func (t ExifTag) String() string {
+	v := t.Value
+	if i := bytes.IndexByte(v, 0); i >= 0 {
+		v = v[:i]
+	}
+	return string(v)
+}
+
+// Ints decodes integer values (BYTE, SHORT, LONG, IFD and their signed
+// forms). It returns nil for other types.
+func (t ExifTag) Ints() []int64 {
+	size := typeSize(t.Type)
+	if size == 0 || t.Type == TypeRational || t.Type == TypeSRational ||
+		t.Type == TypeFloat || t.Type == TypeDouble || t.Type == TypeASCII {
+		return nil
+	}
+
+	order := t.ByteOrder()
+	var out []int64
+	for v := t.Value; len(v) >= size; v = v[size:] {
+		switch t.Type {
+		case TypeByte, TypeUndefined:
+			out = append(out, int64(v[0]))
+		case TypeSByte:
+			out = append(out, int64(int8(v[0])))
+		case TypeShort:
+			out = append(out, int64(order.Uint16(v)))
+		case TypeSShort:
+			out = append(out, int64(int16(order.Uint16(v))))
+		case TypeLong, TypeIFD:
+			out = append(out, int64(order.Uint32(v)))
+		case TypeSLong:
+			out = append(out, int64(int32(order.Uint32(v))))
+		}
+	}
+	return out
+}
+
+// Floats decodes numeric values of any type, including rationals.
+func (t ExifTag) Floats() []float64 {
+	size := typeSize(t.Type)
+	if size == 0 || t.Type == TypeASCII {
+		return nil
+	}
+
+	order := t.ByteOrder()
+	var out []float64
+	switch t.Type {
+	case TypeRational, TypeSRational:
+		for v := t.Value; len(v) >= 8; v = v[8:] {
+			num, den := order.Uint32(v), order.Uint32(v[4:])
+			if t.Type == TypeSRational {
+				out = append(out, float64(int32(num))/float64(int32(den)))
+			} else {
+				out = append(out, float64(num)/float64(den))
+			}
+		}
+	case TypeFloat:
+		for v := t.Value; len(v) >= 4; v = v[4:] {
+			out = append(out, float64(math.Float32frombits(order.Uint32(v))))
+		}
+	case TypeDouble:
+		for v := t.Value; len(v) >= 8; v = v[8:] {
+			out = append(out, math.Float64frombits(order.Uint64(v)))
+		}
+	default:
+		for _, i := range t.Ints() {
+			out = append(out, float64(i))
+		}
+	}
+	return out
+}
+
+func typeSize(typ uint16) int {
+	switch typ {
+	case TypeByte, TypeASCII, TypeSByte, TypeUndefined:
+		return 1
+	case TypeShort, TypeSShort:
+		return 2
+	case TypeLong, TypeSLong, TypeFloat, TypeIFD:
+		return 4
+	case TypeRational, TypeSRational, TypeDouble:
+		return 8
+	}
+	return 0
+}
@@ -6,15 +6,15 @@ import (
 )
 
 type LibRawIData struct {
-	Make             string
-	Model            string
-	MakerIndex       uint
-	Software         string
-	RawCount         uint
-	IsFoveon         bool
-	DngVersion       uint
-	Colors           int
-	ColorDescription [5]rune
+	Make             string  `json:"make"`
+	Model            string  `json:"model"`
+	MakerIndex       uint    `json:"maker_index"`
+	Software         string  `json:"software"`
+	RawCount         uint    `json:"raw_count"`
+	IsFoveon         bool    `json:"is_foveon"`
+	DngVersion       uint    `json:"dng_version"`
+	Colors           int     `json:"colors"`
+	ColorDescription [5]rune `json:"color_description"`
 }
 
 func (idata *LibRawIData) DebugFormat() string {
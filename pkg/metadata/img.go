@@ -3,9 +3,19 @@ package metadata
 import "time"
 
 type ImgMetadata struct {
-	CaptureTimestamp int64
-	CaptureDate      time.Time
+	CaptureTimestamp int64     `json:"capture_timestamp"`
+	CaptureDate      time.Time `json:"capture_date"`
 
-	IData LibRawIData
-	Sizes LibRawSizes
+	IData LibRawIData `json:"idata"`
+	Sizes LibRawSizes `json:"sizes"`
+	Color ColorData   `json:"color"`
+	Other ImgOther    `json:"other"`
+	Lens  LensInfo    `json:"lens"`
+
+	ShootingInfo ShootingInfo `json:"shooting_info"`
+	Makernotes   Makernotes   `json:"makernotes"`
+
+	// ExifTags lists every tag LibRaw parsed, if collection was enabled
+	// with ProcessorOptions.CollectExifTags.
+	ExifTags ExifTags `json:"exif_tags,omitempty"`
 }
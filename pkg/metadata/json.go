@@ -0,0 +1,144 @@
+package metadata
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the JSON representation of ImgMetadata,
+// written as "schema_version". It is incremented whenever a field changes
+// meaning or is removed; new fields may be added within a version.
+const SchemaVersion = 1
+
+//go:embed schema.json
+var jsonSchema []byte
+
+// JSONSchema returns the JSON Schema (draft 2020-12) describing the JSON
+// form of ImgMetadata for SchemaVersion.
+func JSONSchema() []byte {
+	return slices.Clone(jsonSchema)
+}
+
+type imgJSON ImgMetadata
+
+// MarshalJSON adds the schema version and writes the capture date in
+// RFC 3339 (omitted when the file has no timestamp).
+func (m ImgMetadata) MarshalJSON() ([]byte, error) {
+	var date string
+	if m.CaptureTimestamp != 0 {
+		date = m.CaptureDate.Format(time.RFC3339)
+	}
+	return json.Marshal(struct {
+		SchemaVersion int `json:"schema_version"`
+		imgJSON
+		CaptureDate string `json:"capture_date,omitempty"`
+	}{SchemaVersion, imgJSON(m), date})
+}
+
+// UnmarshalJSON reads the form written by MarshalJSON. Documents of a
+// newer schema version are rejected.
+func (m *ImgMetadata) UnmarshalJSON(data []byte) error {
+	var v struct {
+		SchemaVersion int `json:"schema_version"`
+		*imgJSON
+		CaptureDate string `json:"capture_date"`
+	}
+	v.imgJSON = (*imgJSON)(m)
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("metadata: unsupported schema version %d", v.SchemaVersion)
+	}
+
+	m.CaptureDate = time.Time{}
+	if v.CaptureDate != "" {
+		date, err := time.Parse(time.RFC3339, v.CaptureDate)
+		if err != nil {
+			return fmt.Errorf("metadata: capture_date: %w", err)
+		}
+		m.CaptureDate = date
+	}
+	return nil
+}
+
+type idataJSON LibRawIData
+
+// MarshalJSON writes ColorDescription as a string such as "RGBG".
+func (idata LibRawIData) MarshalJSON() ([]byte, error) {
+	cdesc := strings.TrimRight(string(idata.ColorDescription[:]), "\x00")
+	return json.Marshal(struct {
+		idataJSON
+		ColorDescription string `json:"color_description"`
+	}{idataJSON(idata), cdesc})
+}
+
+// UnmarshalJSON reads the form written by MarshalJSON.
+func (idata *LibRawIData) UnmarshalJSON(data []byte) error {
+	var v struct {
+		*idataJSON
+		ColorDescription string `json:"color_description"`
+	}
+	v.idataJSON = (*idataJSON)(idata)
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	cdesc := []rune(v.ColorDescription)
+	if len(cdesc) > len(idata.ColorDescription) {
+		return fmt.Errorf("metadata: color_description %q is too long", v.ColorDescription)
+	}
+	idata.ColorDescription = [5]rune{}
+	copy(idata.ColorDescription[:], cdesc)
+	return nil
+}
+
+type gpsJSON GPSInfo
+
+// MarshalJSON writes the reference letters as strings, empty when unknown.
+func (gps GPSInfo) MarshalJSON() ([]byte, error) {
+	ref := func(b byte) string {
+		if b == 0 {
+			return ""
+		}
+		return string(rune(b))
+	}
+	return json.Marshal(struct {
+		gpsJSON
+		LatitudeRef  string `json:"latitude_ref"`
+		LongitudeRef string `json:"longitude_ref"`
+		Status       string `json:"status"`
+	}{gpsJSON(gps), ref(gps.LatitudeRef), ref(gps.LongitudeRef), ref(gps.Status)})
+}
+
+// UnmarshalJSON reads the form written by MarshalJSON.
+func (gps *GPSInfo) UnmarshalJSON(data []byte) error {
+	var v struct {
+		*gpsJSON
+		LatitudeRef  string `json:"latitude_ref"`
+		LongitudeRef string `json:"longitude_ref"`
+		Status       string `json:"status"`
+	}
+	v.gpsJSON = (*gpsJSON)(gps)
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	for _, f := range []struct {
+		dst *byte
+		s   string
+	}{{&gps.LatitudeRef, v.LatitudeRef}, {&gps.LongitudeRef, v.LongitudeRef}, {&gps.Status, v.Status}} {
+		if len(f.s) > 1 {
+			return fmt.Errorf("metadata: GPS reference %q is not a single letter", f.s)
+		}
+		*f.dst = 0
+		if f.s != "" {
+			*f.dst = f.s[0]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package metadata
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetadataJSON(t *testing.T) {
+	meta := testMetadata()
+	meta.IData.ColorDescription = [5]rune{'R', 'G', 'B', 'G'}
+	meta.Color.BlackPattern = [][]uint32{{1, 2}, {3, 4}}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"schema_version":1`,
+		`"color_description":"RGBG"`,
+		`"capture_date":"2024-05-17T10:30:00`,
+		`"latitude_ref":"N"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON lacks %s:\n%s", want, data)
+		}
+	}
+
+	var back ImgMetadata
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !back.CaptureDate.Equal(meta.CaptureDate) {
+		t.Errorf("capture date = %v, want %v", back.CaptureDate, meta.CaptureDate)
+	}
+	back.CaptureDate = meta.CaptureDate
+	if !reflect.DeepEqual(&back, meta) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", back, *meta)
+	}
+
+	if err := json.Unmarshal([]byte(`{"schema_version":2}`), &back); err == nil {
+		t.Error("newer schema version accepted")
+	}
+}
+
+// TestMetadataMatchesSchema checks the object structure of the JSON form
+// against the properties, required and additionalProperties keywords of
+// the schema, following $refs into $defs.
+func TestMetadataMatchesSchema(t *testing.T) {
+	var schema map[string]any
+	if err := json.Unmarshal(JSONSchema(), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	withNotes := testMetadata()
+	withNotes.Makernotes.Fuji = &FujiMakernotes{FilmMode: 0x600, DevelopmentDynamicRange: 200}
+	withNotes.ExifTags = ExifTags{{ID: 0x829a, Type: TypeRational, Count: 1, Value: make([]byte, 8)}}
+
+	for _, meta := range []*ImgMetadata{testMetadata(), withNotes, {}} {
+		data, err := json.Marshal(meta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		checkObject(t, "", schema, schema, doc)
+	}
+}
+
+func checkObject(t *testing.T, path string, root, schema, doc map[string]any) {
+	t.Helper()
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = root["$defs"].(map[string]any)[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+	}
+	props, _ := schema["properties"].(map[string]any)
+	required, _ := schema["required"].([]any)
+	for _, req := range required {
+		if _, ok := doc[req.(string)]; !ok {
+			t.Errorf("%s: required property %s missing", path, req)
+		}
+	}
+	for key, value := range doc {
+		sub, ok := props[key].(map[string]any)
+		if !ok {
+			t.Errorf("%s: property %s not in schema", path, key)
+			continue
+		}
+		switch value := value.(type) {
+		case map[string]any:
+			checkObject(t, path+"/"+key, root, sub, value)
+		case []any:
+			if items, ok := sub["items"].(map[string]any); ok {
+				for _, item := range value {
+					if obj, ok := item.(map[string]any); ok {
+						checkObject(t, path+"/"+key+"[]", root, items, obj)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestExifTagDecoding(t *testing.T) {
+	be := ExifTag{Type: TypeRational, Count: 2, BigEndian: true,
+		Value: []byte{0, 0, 0, 1, 0, 0, 0, 250, 0, 0, 0, 28, 0, 0, 0, 10}}
+	if got := be.Floats(); len(got) != 2 || got[0] != 1.0/250 || got[1] != 2.8 {
+		t.Errorf("rationals = %v", got)
+	}
+	if be.Ints() != nil {
+		t.Error("rationals decoded as integers")
+	}
+
+	le := ExifTag{Type: TypeSShort, Count: 2, Value: []byte{0xff, 0xff, 0x10, 0x00}}
+	if got := le.Ints(); len(got) != 2 || got[0] != -1 || got[1] != 16 {
+		t.Errorf("signed shorts = %v", got)
+	}
+
+	ascii := ExifTag{Type: TypeASCII, Count: 6, Value: []byte("Canon\x00")}
+	if ascii.String() != "Canon" {
+		t.Errorf("ASCII = %q", ascii.String())
+	}
+
+	tags := ExifTags{{IFD: 2, ID: 1}, {ID: 1, Count: 7}}
+	if tag, ok := tags.Find(0, 1); !ok || tag.Count != 7 {
+		t.Errorf("Find(0, 1) = %+v, %v", tag, ok)
+	}
+}
@@ -0,0 +1,158 @@
+package metadata
+
+// Makernotes holds the vendor specific fields LibRaw decodes from the
+// makernotes (libraw_makernotes_t). Only the vendor matching the camera
+// make is set.
+type Makernotes struct {
+	Canon     *CanonMakernotes     `json:"canon,omitempty"`
+	Nikon     *NikonMakernotes     `json:"nikon,omitempty"`
+	Sony      *SonyMakernotes      `json:"sony,omitempty"`
+	Fuji      *FujiMakernotes      `json:"fuji,omitempty"`
+	Olympus   *OlympusMakernotes   `json:"olympus,omitempty"`
+	Panasonic *PanasonicMakernotes `json:"panasonic,omitempty"`
+	Pentax    *PentaxMakernotes    `json:"pentax,omitempty"`
+}
+
+// CanonMakernotes is a subset of libraw_canon_makernotes_t.
+type CanonMakernotes struct {
+	ColorDataVer    int `json:"color_data_ver"`
+	ColorDataSubVer int `json:"color_data_sub_ver"`
+
+	// sensor size and the borders of its active area, in pixels
+	SensorWidth        int `json:"sensor_width"`
+	SensorHeight       int `json:"sensor_height"`
+	SensorLeftBorder   int `json:"sensor_left_border"`
+	SensorTopBorder    int `json:"sensor_top_border"`
+	SensorRightBorder  int `json:"sensor_right_border"`
+	SensorBottomBorder int `json:"sensor_bottom_border"`
+
+	AverageBlackLevel     int     `json:"average_black_level"`
+	ContinuousDrive       int     `json:"continuous_drive"`
+	MeteringMode          int     `json:"metering_mode"`
+	ExposureMode          int     `json:"exposure_mode"`
+	ImageStabilization    int     `json:"image_stabilization"`
+	RecordMode            int     `json:"record_mode"`
+	SRAWQuality           int     `json:"sraw_quality"`
+	Quality               int     `json:"quality"`
+	CanonLog              int     `json:"canon_log"`
+	HighlightTonePriority int     `json:"highlight_tone_priority"`
+	AutoLightingOptimizer int     `json:"auto_lighting_optimizer"`
+	AFMicroAdjMode        int     `json:"af_micro_adj_mode"`
+	AFMicroAdjValue       float32 `json:"af_micro_adj_value"`
+	RFLensID              int     `json:"rf_lens_id"`
+}
+
+// NikonMakernotes is a subset of libraw_nikon_makernotes_t.
+type NikonMakernotes struct {
+	NEFCompression int       `json:"nef_compression"` // 1 lossy type 1, 2 uncompressed, 3 lossless, 4 lossy type 2, ...
+	NEFBitDepth    [4]uint16 `json:"nef_bit_depth"`
+
+	ExposureMode       int     `json:"exposure_mode"`
+	ExposureProgram    int     `json:"exposure_program"`
+	ShootingMode       uint16  `json:"shooting_mode"`
+	ActiveDLighting    uint16  `json:"active_d_lighting"`
+	VibrationReduction uint8   `json:"vibration_reduction"`
+	VRMode             uint8   `json:"vr_mode"`
+	PictureControlName string  `json:"picture_control_name,omitempty"`
+	PictureControlBase string  `json:"picture_control_base,omitempty"`
+	SensorWidth        uint16  `json:"sensor_width"`
+	SensorHeight       uint16  `json:"sensor_height"`
+	AFFineTune         uint8   `json:"af_fine_tune"`
+	AFFineTuneAdj      int8    `json:"af_fine_tune_adj"`
+	RollAngle          float64 `json:"roll_angle"`
+	PitchAngle         float64 `json:"pitch_angle"`
+	YawAngle           float64 `json:"yaw_angle"`
+}
+
+// SonyMakernotes is a subset of libraw_sony_info_t.
+type SonyMakernotes struct {
+	CameraType      uint16  `json:"camera_type"` // LIBRAW_SONY_CameraType: DSC, DSLR, NEX, SLT, ILCE, ILCA, ...
+	Firmware        float32 `json:"firmware"`
+	SonyRawFileType uint16  `json:"sony_raw_file_type"` // 0 compressed, 1 uncompressed, 2 lossless, 3 lossless compressed
+	RAWFileType     uint16  `json:"raw_file_type"`
+	Quality         uint    `json:"quality"`
+	FileFormat      uint16  `json:"file_format"`
+	MetaVersion     string  `json:"meta_version,omitempty"`
+
+	AFAreaMode                    uint16    `json:"af_area_mode"`
+	AFPointSelected               uint8     `json:"af_point_selected"`
+	AFTracking                    uint8     `json:"af_tracking"`
+	FocusPosition                 uint16    `json:"focus_position"`
+	AFMicroAdjOn                  bool      `json:"af_micro_adj_on"`
+	AFMicroAdjValue               int8      `json:"af_micro_adj_value"`
+	LongExposureNoiseReduction    uint      `json:"long_exposure_noise_reduction"`
+	HighISONoiseReduction         uint16    `json:"high_iso_noise_reduction"`
+	HDR                           [2]uint16 `json:"hdr"`
+	ElectronicFrontCurtainShutter uint      `json:"electronic_front_curtain_shutter"`
+	ShotNumberSincePowerUp        uint      `json:"shot_number_since_power_up"`
+
+	PixelShiftGroupID       uint `json:"pixel_shift_group_id"`
+	PixelShiftShots         int  `json:"pixel_shift_shots"` // number of shots in the pixel shift group
+	PixelShiftNumberInGroup int  `json:"pixel_shift_number_in_group"`
+}
+
+// FujiMakernotes is a subset of libraw_fuji_info_t.
+type FujiMakernotes struct {
+	FilmMode                uint16 `json:"film_mode"` // film simulation, FujiFilm FilmMode tag
+	DynamicRange            uint16 `json:"dynamic_range"`
+	DynamicRangeSetting     uint16 `json:"dynamic_range_setting"`
+	DevelopmentDynamicRange uint16 `json:"development_dynamic_range"` // 100, 200 or 400
+	AutoDynamicRange        uint16 `json:"auto_dynamic_range"`
+	DRangePriority          uint16 `json:"d_range_priority"`
+	DRangePriorityAuto      uint16 `json:"d_range_priority_auto"`
+	DRangePriorityFixed     uint16 `json:"d_range_priority_fixed"`
+
+	FujiModel   string `json:"fuji_model,omitempty"`
+	SensorID    string `json:"sensor_id,omitempty"`
+	RAFVersion  string `json:"raf_version,omitempty"`
+	FocusMode   uint16 `json:"focus_mode"`
+	AFMode      uint16 `json:"af_mode"`
+	ShutterType uint16 `json:"shutter_type"`
+	ExrMode     uint16 `json:"exr_mode"`
+	CropMode    uint16 `json:"crop_mode"`
+	DriveMode   int    `json:"drive_mode"`
+	Rating      uint   `json:"rating"`
+
+	ImageCount       int        `json:"image_count"`
+	PixelShiftOffset [2]float32 `json:"pixel_shift_offset"`
+}
+
+// OlympusMakernotes is a subset of libraw_olympus_makernotes_t.
+type OlympusMakernotes struct {
+	CameraType2   string    `json:"camera_type2,omitempty"`
+	ValidBits     uint16    `json:"valid_bits"`
+	DriveMode     [5]int    `json:"drive_mode"`
+	ColorSpace    uint16    `json:"color_space"`
+	FocusMode     [2]uint16 `json:"focus_mode"`
+	AutoFocus     uint16    `json:"auto_focus"`
+	AFPoint       uint16    `json:"af_point"`
+	AFResult      uint16    `json:"af_result"`
+	SpecialMode   [3]uint   `json:"special_mode"`
+	FocusDistance float64   `json:"focus_distance"`
+	StackedImage  [2]uint   `json:"stacked_image"`
+	IsLiveND      bool      `json:"is_live_nd"`
+	LiveNDFactor  uint      `json:"live_nd_factor"`
+}
+
+// PanasonicMakernotes is a subset of libraw_panasonic_makernotes_t.
+type PanasonicMakernotes struct {
+	Compression       uint16     `json:"compression"`
+	BlackLevelDim     uint16     `json:"black_level_dim"`
+	BlackLevel        [8]float32 `json:"black_level"`
+	Multishot         uint       `json:"multishot"` // non-zero for high resolution (pixel shift) shots
+	Gamma             float32    `json:"gamma"`
+	HighISOMultiplier [3]int     `json:"high_iso_multiplier"`
+}
+
+// PentaxMakernotes is a subset of libraw_pentax_makernotes_t.
+type PentaxMakernotes struct {
+	DriveMode       [4]uint8  `json:"drive_mode"`
+	FocusMode       [2]uint16 `json:"focus_mode"`
+	AFPointSelected [2]uint16 `json:"af_point_selected"`
+	AFPointsInFocus uint      `json:"af_points_in_focus"`
+	FocusPosition   uint16    `json:"focus_position"`
+	AFAdjustment    int16     `json:"af_adjustment"`
+	AFPointMode     uint8     `json:"af_point_mode"`
+	MultiExposure   uint8     `json:"multi_exposure"`
+	Quality         uint16    `json:"quality"`
+}
@@ -0,0 +1,46 @@
+package metadata
+
+// ImgOther holds the exposure settings and descriptive fields of a shot
+// (libraw_imgother_t).
+type ImgOther struct {
+	ISOSpeed    float32 `json:"iso_speed"`
+	Shutter     float32 `json:"shutter"`      // exposure time in seconds
+	Aperture    float32 `json:"aperture"`     // f-number
+	FocalLength float32 `json:"focal_length"` // in mm
+	ShotOrder   uint    `json:"shot_order"`
+
+	Description string `json:"description,omitempty"`
+	Artist      string `json:"artist,omitempty"`
+
+	GPS GPSInfo `json:"gps"`
+}
+
+// GPSInfo is the GPS position recorded by the camera. Coordinates are
+// degrees, minutes and seconds; the reference fields hold the EXIF
+// letters ('N'/'S', 'E'/'W', 'A'/'V') or 0 when unknown.
+type GPSInfo struct {
+	Latitude     [3]float32 `json:"latitude"`
+	Longitude    [3]float32 `json:"longitude"`
+	Timestamp    [3]float32 `json:"timestamp"`    // UTC hours, minutes, seconds
+	Altitude     float32    `json:"altitude"`     // meters
+	AltitudeRef  uint8      `json:"altitude_ref"` // 0 = above sea level, 1 = below
+	LatitudeRef  byte       `json:"latitude_ref"`
+	LongitudeRef byte       `json:"longitude_ref"`
+	Status       byte       `json:"status"`
+	Parsed       bool       `json:"parsed"` // the file carried a GPS IFD
+}
+
+// LensInfo describes the lens a shot was taken with (a subset of
+// libraw_lensinfo_t).
+type LensInfo struct {
+	Make   string `json:"make,omitempty"`
+	Model  string `json:"model,omitempty"`
+	Serial string `json:"serial,omitempty"`
+
+	MinFocal       float32 `json:"min_focal"` // mm
+	MaxFocal       float32 `json:"max_focal"`
+	MaxAp4MinFocal float32 `json:"max_ap4_min_focal"` // largest aperture (smallest f-number) at MinFocal
+	MaxAp4MaxFocal float32 `json:"max_ap4_max_focal"`
+
+	FocalLengthIn35mm uint16 `json:"focal_length_in_35mm"`
+}
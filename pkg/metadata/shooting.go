@@ -0,0 +1,17 @@
+package metadata
+
+// ShootingInfo holds the camera settings of a shot and the serial numbers
+// of the body (libraw_shootinginfo_t). Modes use the vendor's makernote
+// codes; -1 means LibRaw did not find the value.
+type ShootingInfo struct {
+	DriveMode          int `json:"drive_mode"`
+	FocusMode          int `json:"focus_mode"`
+	MeteringMode       int `json:"metering_mode"`
+	AFPoint            int `json:"af_point"`
+	ExposureMode       int `json:"exposure_mode"`
+	ExposureProgram    int `json:"exposure_program"`
+	ImageStabilization int `json:"image_stabilization"`
+
+	BodySerial         string `json:"body_serial,omitempty"`          // serial number printed on the body
+	InternalBodySerial string `json:"internal_body_serial,omitempty"` // serial number of the main board, if different
+}
@@ -3,12 +3,21 @@ package metadata
 import "fmt"
 
 type LibRawSizes struct {
-	RawHeight uint16
-	RawWidth  uint16
-	Height    uint16
-	Width     uint16
-	Iheight   uint16
-	Iwidth    uint16
+	RawHeight uint16 `json:"raw_height"`
+	RawWidth  uint16 `json:"raw_width"`
+	Height    uint16 `json:"height"`
+	Width     uint16 `json:"width"`
+	Iheight   uint16 `json:"iheight"`
+	Iwidth    uint16 `json:"iwidth"`
+
+	TopMargin  uint16 `json:"top_margin"`
+	LeftMargin uint16 `json:"left_margin"`
+	Flip       int    `json:"flip"` // LibRaw rotation code: 0 = none, 3 = 180, 5 = 90 CCW, 6 = 90 CW
+}
+
+// ExifOrientation converts Flip to the EXIF/TIFF Orientation tag value.
+func (sizes *LibRawSizes) ExifOrientation() int {
+	return [8]int{1, 2, 4, 3, 5, 8, 6, 7}[sizes.Flip&7]
 }
 
 func (sizes *LibRawSizes) DebugFormat() string {
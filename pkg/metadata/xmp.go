@@ -0,0 +1,313 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// XMP namespaces used in sidecars.
+const (
+	NamespaceRDF  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	NamespaceXMP  = "http://ns.adobe.com/xap/1.0/"
+	NamespaceTIFF = "http://ns.adobe.com/tiff/1.0/"
+	NamespaceEXIF = "http://ns.adobe.com/exif/1.0/"
+	NamespaceAux  = "http://ns.adobe.com/exif/1.0/aux/"
+)
+
+// xmpDateLayout is the XMP date format. Capture times carry no zone since
+// cameras record local time.
+const xmpDateLayout = "2006-01-02T15:04:05"
+
+// MarshalXMP serializes the camera and its serial number, lens, exposure,
+// GPS position, dimensions and capture date of meta as an XMP packet using the tiff,
+// exif and aux namespaces. Fields LibRaw did not find are left out.
+func MarshalXMP(meta *ImgMetadata) []byte {
+	var p xmpProps
+
+	p.add("tiff:Make", meta.IData.Make)
+	p.add("tiff:Model", meta.IData.Model)
+	if meta.Sizes.Width > 0 && meta.Sizes.Height > 0 {
+		p.add("tiff:ImageWidth", fmt.Sprint(meta.Sizes.Width))
+		p.add("tiff:ImageLength", fmt.Sprint(meta.Sizes.Height))
+		p.add("exif:PixelXDimension", fmt.Sprint(meta.Sizes.Width))
+		p.add("exif:PixelYDimension", fmt.Sprint(meta.Sizes.Height))
+	}
+	p.add("tiff:Orientation", fmt.Sprint(meta.Sizes.ExifOrientation()))
+	p.add("tiff:Software", meta.IData.Software)
+	p.add("tiff:Artist", meta.Other.Artist)
+	p.add("tiff:ImageDescription", meta.Other.Description)
+
+	if meta.CaptureTimestamp != 0 {
+		date := meta.CaptureDate.Format(xmpDateLayout)
+		p.add("exif:DateTimeOriginal", date)
+		p.add("xmp:CreateDate", date)
+	}
+
+	other := &meta.Other
+	if other.Shutter > 0 {
+		p.add("exif:ExposureTime", xmpExposureTime(other.Shutter))
+	}
+	if other.Aperture > 0 {
+		p.add("exif:FNumber", xmpRational(other.Aperture, 10))
+	}
+	if other.ISOSpeed > 0 {
+		p.seq("exif:ISOSpeedRatings", strconv.Itoa(int(math.Round(float64(other.ISOSpeed)))))
+	}
+	if other.FocalLength > 0 {
+		p.add("exif:FocalLength", xmpRational(other.FocalLength, 10))
+	}
+	if meta.Lens.FocalLengthIn35mm > 0 {
+		p.add("exif:FocalLengthIn35mmFilm", fmt.Sprint(meta.Lens.FocalLengthIn35mm))
+	}
+
+	if gps := &other.GPS; gps.Parsed {
+		p.add("exif:GPSVersionID", "2.3.0.0")
+		if gps.LatitudeRef != 0 {
+			p.add("exif:GPSLatitude", xmpCoordinate(gps.Latitude, gps.LatitudeRef))
+		}
+		if gps.LongitudeRef != 0 {
+			p.add("exif:GPSLongitude", xmpCoordinate(gps.Longitude, gps.LongitudeRef))
+		}
+		if gps.Altitude != 0 {
+			p.add("exif:GPSAltitudeRef", fmt.Sprint(gps.AltitudeRef))
+			p.add("exif:GPSAltitude", xmpRational(gps.Altitude, 100))
+		}
+	}
+
+	p.add("aux:SerialNumber", meta.ShootingInfo.BodySerial)
+	lens := &meta.Lens
+	p.add("aux:Lens", lens.Model)
+	p.add("aux:LensSerialNumber", lens.Serial)
+	if lens.MinFocal > 0 && lens.MaxFocal > 0 {
+		p.add("aux:LensInfo", strings.Join([]string{
+			xmpRational(lens.MinFocal, 10), xmpRational(lens.MaxFocal, 10),
+			xmpRational(lens.MaxAp4MinFocal, 10), xmpRational(lens.MaxAp4MaxFocal, 10),
+		}, " "))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	buf.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	fmt.Fprintf(&buf, " <rdf:RDF xmlns:rdf=%q>\n", NamespaceRDF)
+	fmt.Fprintf(&buf, "  <rdf:Description rdf:about=\"\"\n    xmlns:xmp=%q\n    xmlns:tiff=%q\n    xmlns:exif=%q\n    xmlns:aux=%q>\n",
+		NamespaceXMP, NamespaceTIFF, NamespaceEXIF, NamespaceAux)
+	buf.Write(p.buf.Bytes())
+	buf.WriteString("  </rdf:Description>\n </rdf:RDF>\n</x:xmpmeta>\n")
+	buf.WriteString("<?xpacket end=\"w\"?>\n")
+	return buf.Bytes()
+}
+
+// SidecarPath returns the path of the XMP sidecar of a RAW file:
+// the file name with its extension replaced by .xmp.
+func SidecarPath(rawPath string) string {
+	return strings.TrimSuffix(rawPath, filepath.Ext(rawPath)) + ".xmp"
+}
+
+// WriteXMPSidecar writes MarshalXMP(meta) next to the RAW file and returns
+// the path written. An existing sidecar is only replaced if overwrite is
+// set; otherwise an error matching fs.ErrExist is returned.
+func WriteXMPSidecar(rawPath string, meta *ImgMetadata, overwrite bool) (string, error) {
+	path := SidecarPath(rawPath)
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(MarshalXMP(meta)); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// xmpProps accumulates the properties of an rdf:Description.
+type xmpProps struct {
+	buf bytes.Buffer
+}
+
+func (p *xmpProps) add(name, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(&p.buf, "   <%s>", name)
+	xml.EscapeText(&p.buf, []byte(value))
+	fmt.Fprintf(&p.buf, "</%s>\n", name)
+}
+
+func (p *xmpProps) seq(name string, values ...string) {
+	fmt.Fprintf(&p.buf, "   <%s>\n    <rdf:Seq>\n", name)
+	for _, v := range values {
+		p.buf.WriteString("     <rdf:li>")
+		xml.EscapeText(&p.buf, []byte(v))
+		p.buf.WriteString("</rdf:li>\n")
+	}
+	fmt.Fprintf(&p.buf, "    </rdf:Seq>\n   </%s>\n", name)
+}
+
+func xmpRational(v float32, denom int) string {
+	return fmt.Sprintf("%d/%d", int64(math.Round(float64(v)*float64(denom))), denom)
+}
+
+// xmpExposureTime writes short exposures as 1/n, the way cameras do.
+func xmpExposureTime(s float32) string {
+	if s < 1 {
+		if n := math.Round(1 / float64(s)); math.Abs(1/n-float64(s)) < 1e-3*float64(s) {
+			return fmt.Sprintf("1/%d", int64(n))
+		}
+		return xmpRational(s, 100000)
+	}
+	return xmpRational(s, 10)
+}
+
+// xmpCoordinate formats a GPS coordinate as "DDD,MM.mmmmmmR", the XMP
+// form of the EXIF degrees, minutes and seconds.
+func xmpCoordinate(dms [3]float32, ref byte) string {
+	deg := math.Trunc(float64(dms[0]))
+	minutes := (float64(dms[0])-deg)*60 + float64(dms[1]) + float64(dms[2])/60
+	return fmt.Sprintf("%d,%.6f%c", int(deg), minutes, ref)
+}
+
+// NamespaceCRS is the Camera Raw Settings namespace Lightroom and Camera
+// Raw store their develop settings in.
+const NamespaceCRS = "http://ns.adobe.com/camera-raw-settings/1.0/"
+
+// White balance modes of crs:WhiteBalance.
+const (
+	WhiteBalanceAsShot = "As Shot"
+	WhiteBalanceAuto   = "Auto"
+	WhiteBalanceCustom = "Custom"
+)
+
+// DevelopSettings are the develop adjustments a sidecar records that can
+// be reproduced by LibRaw. Unset fields keep their zero value.
+type DevelopSettings struct {
+	// Crop is the crop rectangle as fractions of the unrotated image, nil
+	// when the image is not cropped.
+	Crop *CropRect
+
+	// WhiteBalance is the crs:WhiteBalance mode. Temperature (in Kelvin)
+	// and Tint are set for every mode but As Shot.
+	WhiteBalance string
+	Temperature  float64
+	Tint         float64
+
+	// Exposure is the exposure adjustment in EV (crs:Exposure2012, or
+	// crs:Exposure of older process versions), nil when absent.
+	Exposure *float64
+
+	// Orientation is the EXIF orientation (tiff:Orientation), 0 when absent.
+	Orientation int
+}
+
+// CropRect is a crop rectangle as fractions (0 to 1) of the image.
+type CropRect struct {
+	Top, Left, Bottom, Right float64
+
+	// Angle is the straightening angle in degrees (crs:CropAngle). When it
+	// is not 0 the edges are those of the rotated crop, not a rectangle
+	// of the image.
+	Angle float64
+}
+
+// ParseXMP reads the develop settings from an XMP packet. Properties may
+// be given either as attributes of rdf:Description, as Lightroom writes
+// them, or as child elements.
+func ParseXMP(data []byte) (*DevelopSettings, error) {
+	props := map[string]string{}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var stack []xml.Name
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse XMP: %w", err)
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if tok.Name.Space == NamespaceRDF && tok.Name.Local == "Description" {
+				for _, attr := range tok.Attr {
+					props[attr.Name.Space+attr.Name.Local] = attr.Value
+				}
+			}
+			stack = append(stack, tok.Name)
+			text.Reset()
+		case xml.CharData:
+			text.Write(tok)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			// simple properties are direct children of rdf:Description
+			if n := len(stack); n > 0 && stack[n-1].Space == NamespaceRDF && stack[n-1].Local == "Description" {
+				if v := strings.TrimSpace(text.String()); v != "" {
+					props[tok.Name.Space+tok.Name.Local] = v
+				}
+			}
+			text.Reset()
+		}
+	}
+
+	s := &DevelopSettings{}
+	num := func(name string) (float64, bool) {
+		v, ok := props[name]
+		if !ok {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+
+	if props[NamespaceCRS+"HasCrop"] == "True" {
+		var crop CropRect
+		var ok [4]bool
+		crop.Top, ok[0] = num(NamespaceCRS + "CropTop")
+		crop.Left, ok[1] = num(NamespaceCRS + "CropLeft")
+		crop.Bottom, ok[2] = num(NamespaceCRS + "CropBottom")
+		crop.Right, ok[3] = num(NamespaceCRS + "CropRight")
+		crop.Angle, _ = num(NamespaceCRS + "CropAngle")
+		if ok == [4]bool{true, true, true, true} && crop.Bottom > crop.Top && crop.Right > crop.Left {
+			s.Crop = &crop
+		}
+	}
+
+	s.WhiteBalance = props[NamespaceCRS+"WhiteBalance"]
+	if s.WhiteBalance != WhiteBalanceAsShot {
+		s.Temperature, _ = num(NamespaceCRS + "Temperature")
+		s.Tint, _ = num(NamespaceCRS + "Tint")
+	}
+
+	if ev, ok := num(NamespaceCRS + "Exposure2012"); ok {
+		s.Exposure = &ev
+	} else if ev, ok := num(NamespaceCRS + "Exposure"); ok {
+		s.Exposure = &ev
+	}
+
+	if o, ok := num(NamespaceTIFF + "Orientation"); ok && o >= 1 && o <= 8 {
+		s.Orientation = int(o)
+	}
+
+	return s, nil
+}
+
+// ReadXMPSidecar parses the sidecar of a RAW file, see SidecarPath.
+func ReadXMPSidecar(rawPath string) (*DevelopSettings, error) {
+	data, err := os.ReadFile(SidecarPath(rawPath))
+	if err != nil {
+		return nil, err
+	}
+	return ParseXMP(data)
+}
@@ -0,0 +1,183 @@
+package metadata
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testMetadata() *ImgMetadata {
+	date := time.Date(2024, 5, 17, 10, 30, 0, 0, time.Local)
+	return &ImgMetadata{
+		CaptureTimestamp: date.Unix(),
+		CaptureDate:      date,
+		IData:            LibRawIData{Make: "Nikon", Model: "Z 8 <test & co>"},
+		Sizes:            LibRawSizes{Width: 8256, Height: 5504, Flip: 6},
+		Other: ImgOther{
+			ISOSpeed: 64, Shutter: 1.0 / 125, Aperture: 8, FocalLength: 50,
+			GPS: GPSInfo{
+				Latitude: [3]float32{48, 51, 30}, LatitudeRef: 'N',
+				Longitude: [3]float32{2, 17, 24}, LongitudeRef: 'E',
+				Parsed: true,
+			},
+		},
+		ShootingInfo: ShootingInfo{BodySerial: "3012345"},
+		Lens:         LensInfo{Model: "NIKKOR Z 50mm f/1.8 S", MinFocal: 50, MaxFocal: 50, MaxAp4MinFocal: 1.8, MaxAp4MaxFocal: 1.8},
+	}
+}
+
+func TestMarshalXMP(t *testing.T) {
+	packet := MarshalXMP(testMetadata())
+
+	// the packet must be well formed XML
+	dec := xml.NewDecoder(strings.NewReader(string(packet)))
+	props := map[string]string{}
+	var name string
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("invalid XML: %v", err)
+			}
+			break
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			name = tok.Name.Space + tok.Name.Local
+		case xml.CharData:
+			if s := strings.TrimSpace(string(tok)); s != "" {
+				props[name] = s
+			}
+		}
+	}
+
+	for prop, want := range map[string]string{
+		NamespaceTIFF + "Model":            "Z 8 <test & co>",
+		NamespaceTIFF + "Orientation":      "6",
+		NamespaceEXIF + "ExposureTime":     "1/125",
+		NamespaceEXIF + "FNumber":          "80/10",
+		NamespaceEXIF + "GPSLatitude":      "48,51.500000N",
+		NamespaceEXIF + "GPSLongitude":     "2,17.400000E",
+		NamespaceEXIF + "DateTimeOriginal": "2024-05-17T10:30:00",
+		NamespaceAux + "SerialNumber":      "3012345",
+		NamespaceAux + "Lens":              "NIKKOR Z 50mm f/1.8 S",
+		NamespaceAux + "LensInfo":          "500/10 500/10 18/10 18/10",
+		NamespaceRDF + "li":                "64",
+	} {
+		if got := props[prop]; got != want {
+			t.Errorf("%s = %q, want %q", prop, got, want)
+		}
+	}
+
+	// extended ISO stays an XMP Integer
+	meta := testMetadata()
+	meta.Other.ISOSpeed = 3276800
+	if packet := string(MarshalXMP(meta)); !strings.Contains(packet, "<rdf:li>3276800</rdf:li>") {
+		t.Errorf("ISO 3276800 not written as an integer:\n%s", packet)
+	}
+}
+
+func TestWriteXMPSidecar(t *testing.T) {
+	raw := filepath.Join(t.TempDir(), "DSC_0001.NEF")
+
+	path, err := WriteXMPSidecar(raw, testMetadata(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.TrimSuffix(raw, ".NEF") + ".xmp"; path != want {
+		t.Errorf("sidecar written to %s, want %s", path, want)
+	}
+
+	if _, err := WriteXMPSidecar(raw, &ImgMetadata{}, false); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("existing sidecar not protected: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "Nikon") {
+		t.Error("existing sidecar was modified")
+	}
+
+	if _, err := WriteXMPSidecar(raw, &ImgMetadata{}, true); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "Nikon") {
+		t.Error("sidecar not overwritten")
+	}
+}
+
+const lightroomSidecar = `<x:xmpmeta xmlns:x="adobe:ns:meta/" x:xmptk="Adobe XMP Core 7.0-c000">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:tiff="http://ns.adobe.com/tiff/1.0/"
+    xmlns:crs="http://ns.adobe.com/camera-raw-settings/1.0/"
+   crs:WhiteBalance="Custom"
+   crs:Temperature="5450"
+   crs:Tint="+12"
+   crs:Exposure2012="+0.65"
+   crs:CropTop="0.1"
+   crs:CropLeft="0.05"
+   crs:CropBottom="0.9"
+   crs:CropRight="0.95"
+   crs:CropAngle="0"
+   crs:HasCrop="True">
+   <tiff:Orientation>8</tiff:Orientation>
+   <crs:ToneCurvePV2012>
+    <rdf:Seq>
+     <rdf:li>0, 0</rdf:li>
+     <rdf:li>255, 255</rdf:li>
+    </rdf:Seq>
+   </crs:ToneCurvePV2012>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+func TestParseXMP(t *testing.T) {
+	s, err := ParseXMP([]byte(lightroomSidecar))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Crop == nil || *s.Crop != (CropRect{Top: 0.1, Left: 0.05, Bottom: 0.9, Right: 0.95}) {
+		t.Errorf("crop = %+v", s.Crop)
+	}
+	if s.WhiteBalance != WhiteBalanceCustom || s.Temperature != 5450 || s.Tint != 12 {
+		t.Errorf("white balance = %q %v/%v", s.WhiteBalance, s.Temperature, s.Tint)
+	}
+	if s.Exposure == nil || *s.Exposure != 0.65 {
+		t.Errorf("exposure = %v", s.Exposure)
+	}
+	if s.Orientation != 8 {
+		t.Errorf("orientation = %d, want 8", s.Orientation)
+	}
+
+	// a crop that is switched off is ignored
+	s, err = ParseXMP([]byte(strings.Replace(lightroomSidecar, `crs:HasCrop="True"`, `crs:HasCrop="False"`, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Crop != nil {
+		t.Errorf("crop = %+v, want none", s.Crop)
+	}
+
+	s, err = ParseXMP([]byte(strings.Replace(lightroomSidecar, `crs:CropAngle="0"`, `crs:CropAngle="-2.35"`, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Crop == nil || s.Crop.Angle != -2.35 {
+		t.Errorf("straightened crop = %+v", s.Crop)
+	}
+}
+
+func TestParseXMPOwnOutput(t *testing.T) {
+	s, err := ParseXMP(MarshalXMP(testMetadata()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Orientation != 6 {
+		t.Errorf("orientation = %d, want 6", s.Orientation)
+	}
+}
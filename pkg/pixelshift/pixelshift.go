@@ -0,0 +1,232 @@
+// Package pixelshift combines the sub-frames of a pixel shift capture,
+// taken with the sensor moved by one (or half a) pixel between frames,
+// into an image that has every color measured at every pixel, so no
+// demosaicing is needed.
+package pixelshift
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+// Frame is the visible area of one sub-frame of a Bayer sensor, with the
+// black level subtracted.
+type Frame struct {
+	Width  int
+	Height int
+	Pix    []float32 // Width*Height samples, row major
+
+	// Pattern is the color (0 = red, 1 = green, 2 = blue) of the 2x2
+	// Bayer pattern, indexed [row&1][col&1].
+	Pattern [2][2]int
+}
+
+// Options control how frames are combined. The zero value composites
+// four frames with the default offsets and no motion detection.
+type Options struct {
+	// Offsets is the position of each frame's top-left pixel in output
+	// pixels. Nil selects DefaultOffsets.
+	Offsets []image.Point
+
+	// Scale is the number of output pixels per sensor pixel along each
+	// axis: 1 for four frames shifted by whole pixels, 2 for sixteen
+	// frames that include half pixel shifts. 0 selects 2 for sixteen
+	// frames and 1 otherwise.
+	Scale int
+
+	// MotionThreshold enables motion detection: where the green samples
+	// of different frames differ by more than this fraction of their
+	// mean (plus a small noise allowance), the pixel is taken from the
+	// Reference frame alone and interpolated. 0 disables detection.
+	MotionThreshold float32
+
+	// Reference is the frame used in moving areas.
+	Reference int
+}
+
+// DefaultOffsets returns the frame positions of the usual shift sequence,
+// one pixel right, down, then left. For sixteen frames (scale 2) that
+// sequence is repeated from each of the four half pixel positions in the
+// same order.
+func DefaultOffsets(frames int) ([]image.Point, error) {
+	cycle := []image.Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+	switch frames {
+	case 4:
+		return cycle, nil
+	case 16:
+		offsets := make([]image.Point, 0, 16)
+		for _, half := range cycle {
+			for _, full := range cycle {
+				offsets = append(offsets, full.Mul(2).Add(half))
+			}
+		}
+		return offsets, nil
+	}
+	return nil, fmt.Errorf("pixelshift: no default offsets for %d frames", frames)
+}
+
+// Image is a composited image in the units of the input frames.
+type Image struct {
+	Width  int
+	Height int
+	Pix    []float32 // red, green and blue per pixel, row major
+
+	// Motion marks the pixels taken from the reference frame alone (255),
+	// nil if motion detection was off.
+	Motion *image.Gray
+}
+
+// RGB returns the color of the pixel at x, y.
+func (m *Image) RGB(x, y int) [3]float32 {
+	i := 3 * (y*m.Width + x)
+	return [3]float32{m.Pix[i], m.Pix[i+1], m.Pix[i+2]}
+}
+
+// noiseFloor is added to the motion threshold so that dark, noisy areas
+// are not mistaken for motion.
+const noiseFloor = 1.0 / 256
+
+// Composite combines frames into one image. The output covers the area
+// seen by every frame, so it is smaller than Scale times the frame size
+// by the spread of the offsets.
+func Composite(frames []Frame, opts Options) (*Image, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("pixelshift: no frames")
+	}
+	offsets := opts.Offsets
+	if offsets == nil {
+		var err error
+		if offsets, err = DefaultOffsets(len(frames)); err != nil {
+			return nil, err
+		}
+	}
+	if len(offsets) != len(frames) {
+		return nil, fmt.Errorf("pixelshift: %d offsets for %d frames", len(offsets), len(frames))
+	}
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1
+		if len(frames) == 16 {
+			scale = 2
+		}
+	}
+	if opts.Reference < 0 || opts.Reference >= len(frames) {
+		return nil, fmt.Errorf("pixelshift: reference frame %d out of range", opts.Reference)
+	}
+
+	w, h := frames[0].Width, frames[0].Height
+	for i, f := range frames {
+		if f.Width != w || f.Height != h || len(f.Pix) < w*h {
+			return nil, fmt.Errorf("pixelshift: frame %d size differs from frame 0", i)
+		}
+	}
+
+	// the output area covered by all frames
+	area := image.Rect(math.MinInt32, math.MinInt32, math.MaxInt32, math.MaxInt32)
+	for _, off := range offsets {
+		area = area.Intersect(image.Rectangle{off, off.Add(image.Pt(w*scale, h*scale))})
+	}
+	if area.Empty() {
+		return nil, errors.New("pixelshift: frames do not overlap")
+	}
+
+	out := &Image{Width: area.Dx(), Height: area.Dy(), Pix: make([]float32, 3*area.Dx()*area.Dy())}
+	var moving []bool
+	if opts.MotionThreshold > 0 {
+		moving = make([]bool, out.Width*out.Height)
+	}
+
+	for y := range out.Height {
+		for x := range out.Width {
+			var sum [3]float32
+			var n [3]int
+			gmin, gmax := float32(math.Inf(1)), float32(math.Inf(-1))
+			for i, f := range frames {
+				sx := floorDiv(area.Min.X+x-offsets[i].X, scale)
+				sy := floorDiv(area.Min.Y+y-offsets[i].Y, scale)
+				v := f.Pix[sy*w+sx]
+				c := f.Pattern[sy&1][sx&1]
+				sum[c] += v
+				n[c]++
+				if c == 1 {
+					gmin, gmax = min(gmin, v), max(gmax, v)
+				}
+			}
+			if n[0] == 0 || n[1] == 0 || n[2] == 0 {
+				return nil, fmt.Errorf("pixelshift: offsets do not sample every color at %d,%d", x, y)
+			}
+
+			dst := out.Pix[3*(y*out.Width+x):]
+			for c := range 3 {
+				dst[c] = sum[c] / float32(n[c])
+			}
+			if moving != nil && n[1] > 1 {
+				moving[y*out.Width+x] = gmax-gmin > opts.MotionThreshold*dst[1]+noiseFloor
+			}
+		}
+	}
+
+	if moving != nil {
+		out.Motion = image.NewGray(image.Rect(0, 0, out.Width, out.Height))
+		ref := &frames[opts.Reference]
+		off := offsets[opts.Reference]
+		for y := range out.Height {
+			for x := range out.Width {
+				if !dilated(moving, out.Width, out.Height, x, y) {
+					continue
+				}
+				out.Motion.Pix[y*out.Motion.Stride+x] = 255
+				sx := floorDiv(area.Min.X+x-off.X, scale)
+				sy := floorDiv(area.Min.Y+y-off.Y, scale)
+				rgb := ref.interpolate(sx, sy)
+				copy(out.Pix[3*(y*out.Width+x):], rgb[:])
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// dilated reports whether the pixel or one of its neighbours is moving,
+// so that the edges of moving objects are covered too.
+func dilated(moving []bool, w, h, x, y int) bool {
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if x+dx >= 0 && x+dx < w && y+dy >= 0 && y+dy < h && moving[(y+dy)*w+x+dx] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// interpolate estimates all colors at a sensor pixel by averaging the
+// samples of each color in its 3x3 neighbourhood (bilinear demosaicing).
+func (f *Frame) interpolate(sx, sy int) [3]float32 {
+	var sum [3]float32
+	var n [3]int
+	for y := max(sy-1, 0); y <= min(sy+1, f.Height-1); y++ {
+		for x := max(sx-1, 0); x <= min(sx+1, f.Width-1); x++ {
+			c := f.Pattern[y&1][x&1]
+			sum[c] += f.Pix[y*f.Width+x]
+			n[c]++
+		}
+	}
+	var rgb [3]float32
+	for c := range 3 {
+		if n[c] > 0 {
+			rgb[c] = sum[c] / float32(n[c])
+		}
+	}
+	return rgb
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,114 @@
+package pixelshift
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+var rggb = [2][2]int{{0, 1}, {1, 2}}
+
+// scene is a smooth test image, defined in output pixels.
+func scene(x, y int) [3]float32 {
+	return [3]float32{
+		0.2 + 0.5*float32(math.Sin(float64(x)/7)),
+		0.5 + 0.3*float32(math.Cos(float64(y)/5)),
+		float32(x+y) / 200,
+	}
+}
+
+// capture simulates the frames a pixel shift camera takes of a scene.
+func capture(w, h, scale int, offsets []image.Point, sceneAt func(frame, x, y int) [3]float32) []Frame {
+	frames := make([]Frame, len(offsets))
+	for i, off := range offsets {
+		f := Frame{Width: w, Height: h, Pix: make([]float32, w*h), Pattern: rggb}
+		for sy := range h {
+			for sx := range w {
+				f.Pix[sy*w+sx] = sceneAt(i, sx*scale+off.X, sy*scale+off.Y)[rggb[sy&1][sx&1]]
+			}
+		}
+		frames[i] = f
+	}
+	return frames
+}
+
+func TestComposite(t *testing.T) {
+	offsets, _ := DefaultOffsets(4)
+	frames := capture(40, 30, 1, offsets, func(_, x, y int) [3]float32 { return scene(x, y) })
+
+	img, err := Composite(frames, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width != 39 || img.Height != 29 {
+		t.Fatalf("size %dx%d, want 39x29", img.Width, img.Height)
+	}
+	for y := range img.Height {
+		for x := range img.Width {
+			// the output starts at the largest offset, (1, 1)
+			if got, want := img.RGB(x, y), scene(x+1, y+1); got != want {
+				t.Fatalf("pixel %d,%d = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestComposite16(t *testing.T) {
+	offsets, _ := DefaultOffsets(16)
+	frames := capture(20, 16, 2, offsets, func(_, x, y int) [3]float32 { return scene(x/2, y/2) })
+
+	img, err := Composite(frames, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width != 37 || img.Height != 29 {
+		t.Fatalf("size %dx%d, want 37x29", img.Width, img.Height)
+	}
+}
+
+func TestMotionFallback(t *testing.T) {
+	offsets, _ := DefaultOffsets(4)
+	moved := image.Rect(20, 10, 26, 16)
+	frames := capture(40, 30, 1, offsets, func(frame, x, y int) [3]float32 {
+		if frame == 2 && image.Pt(x, y).In(moved) {
+			return [3]float32{1, 1, 1} // something bright passed through
+		}
+		return [3]float32{0.3, 0.4, 0.5}
+	})
+
+	img, err := Composite(frames, Options{MotionThreshold: 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := range img.Height {
+		for x := range img.Width {
+			inside := image.Pt(x+1, y+1).In(moved.Inset(1))
+			far := !image.Pt(x+1, y+1).In(moved.Inset(-3))
+			m := img.Motion.GrayAt(x, y).Y
+			if inside && m == 0 {
+				t.Fatalf("motion at %d,%d not detected", x, y)
+			}
+			if far && m != 0 {
+				t.Fatalf("motion detected at %d,%d", x, y)
+			}
+			if m != 0 && inside {
+				if got := img.RGB(x, y); got != [3]float32{0.3, 0.4, 0.5} {
+					t.Fatalf("moving pixel %d,%d = %v, want the reference frame", x, y, got)
+				}
+			}
+		}
+	}
+}
+
+func TestCompositeErrors(t *testing.T) {
+	offsets, _ := DefaultOffsets(4)
+	frames := capture(8, 8, 1, offsets, func(_, x, y int) [3]float32 { return scene(x, y) })
+
+	if _, err := Composite(frames[:3], Options{}); err == nil {
+		t.Error("three frames without offsets accepted")
+	}
+	same := []image.Point{{0, 0}, {0, 0}, {0, 0}, {0, 0}}
+	if _, err := Composite(frames, Options{Offsets: same}); err == nil {
+		t.Error("offsets that miss colors accepted")
+	}
+}
@@ -0,0 +1,348 @@
+// Package rawserver serves previews, renders and metadata of the RAW files
+// below a directory over HTTP:
+//
+//	GET /thumb?path=2024/IMG_0001.CR2&size=512
+//	GET /render?path=2024/IMG_0001.CR2&preset=camera-look&w=1600&format=png
+//	GET /meta?path=2024/IMG_0001.CR2&unpack=1
+//
+// Paths are relative to the root and may not leave it, also not through
+// symbolic links. Responses carry an ETag derived from the file's size and
+// modification time and the request options, so browsers revalidate
+// cheaply. Decoding stops when the client goes away.
+package rawserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	libraw "github.com/stmtc233/go-libraw"
+	"github.com/stmtc233/go-libraw/pkg/export"
+)
+
+// Config configures a Handler. Only Root is required.
+type Config struct {
+	Root string // directory whose files are served
+
+	// Options are used by /render without a preset and to render /thumb
+	// for files without an embedded preview. Nil selects
+	// libraw.NewProcessorOptions.
+	Options *libraw.ProcessorOptions
+
+	MaxConcurrent int // LibRaw calls in flight; 0 selects runtime.NumCPU
+	ThumbSize     int // default size of /thumb; 0 selects 256
+	JPEGQuality   int // 0 selects 90
+
+	// ErrorLog receives the errors answered with a bare 500, which may
+	// name server paths. Nil logs with the log package.
+	ErrorLog *log.Logger
+}
+
+// maxSize bounds the size and w parameters.
+const maxSize = 16384
+
+// Handler is an http.Handler serving the files below Config.Root.
+type Handler struct {
+	cfg  Config
+	root string // absolute, symbolic links resolved
+	opts libraw.ProcessorOptions
+	sem  chan struct{}
+	mux  *http.ServeMux
+}
+
+// New returns a Handler for cfg.
+func New(cfg Config) (*Handler, error) {
+	root, err := filepath.Abs(cfg.Root)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	opts := libraw.NewProcessorOptions()
+	if cfg.Options != nil {
+		opts = *cfg.Options
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = runtime.NumCPU()
+	}
+	if cfg.ThumbSize <= 0 {
+		cfg.ThumbSize = 256
+	}
+	if cfg.JPEGQuality <= 0 {
+		cfg.JPEGQuality = 90
+	}
+
+	h := &Handler{
+		cfg:  cfg,
+		root: root,
+		opts: opts,
+		sem:  make(chan struct{}, cfg.MaxConcurrent),
+		mux:  http.NewServeMux(),
+	}
+	h.mux.HandleFunc("GET /thumb", h.thumb)
+	h.mux.HandleFunc("GET /render", h.render)
+	h.mux.HandleFunc("GET /meta", h.meta)
+	return h, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// httpError is an error with the status it is reported with.
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+func errorf(status int, format string, args ...any) error {
+	return &httpError{status, fmt.Sprintf(format, args...)}
+}
+
+// file is a request's RAW file.
+type file struct {
+	rel  string // as requested, cleaned
+	path string // on disk
+	info fs.FileInfo
+}
+
+// resolve finds the file named by the path parameter, making sure it is
+// below the root.
+func (h *Handler) resolve(r *http.Request) (*file, error) {
+	rel := r.URL.Query().Get("path")
+	if rel == "" {
+		return nil, errorf(http.StatusBadRequest, "missing path")
+	}
+	rel = path.Clean(strings.TrimPrefix(rel, "/"))
+	if !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return nil, errorf(http.StatusBadRequest, "invalid path")
+	}
+
+	p, err := filepath.EvalSymlinks(filepath.Join(h.root, filepath.FromSlash(rel)))
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return nil, errorf(http.StatusNotFound, "%s not found", rel)
+	} else if err != nil {
+		return nil, err
+	}
+	if inside, err := filepath.Rel(h.root, p); err != nil || !filepath.IsLocal(inside) {
+		return nil, errorf(http.StatusForbidden, "%s is outside the root", rel)
+	}
+
+	st, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !st.Mode().IsRegular() {
+		return nil, errorf(http.StatusNotFound, "%s is not a file", rel)
+	}
+	return &file{rel: rel, path: p, info: st}, nil
+}
+
+// etag identifies a response by the file's identity and the options
+// that shape the output.
+func (f *file) etag(endpoint string, options ...any) string {
+	sum := sha256.New()
+	fmt.Fprintf(sum, "%s\x00%s\x00%d\x00%d", endpoint, f.rel, f.info.Size(), f.info.ModTime().UnixNano())
+	for _, o := range options {
+		fmt.Fprintf(sum, "\x00%#v", o)
+	}
+	return `"` + hex.EncodeToString(sum.Sum(nil)[:16]) + `"`
+}
+
+// notModified sets the ETag and reports whether the client has it.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag != "" && (tag == etag || tag == "*") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// serve handles a request: it resolves the file, answers revalidations
+// and runs produce under the concurrency limit. produce returns the body
+// and its content type.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, etag func(f *file) (string, error),
+	produce func(f *file) ([]byte, string, error)) {
+
+	err := func() error {
+		f, err := h.resolve(r)
+		if err != nil {
+			return err
+		}
+		tag, err := etag(f)
+		if err != nil {
+			return err
+		}
+		if notModified(w, r, tag) {
+			return nil
+		}
+
+		select {
+		case h.sem <- struct{}{}:
+			defer func() { <-h.sem }()
+		case <-r.Context().Done():
+			return r.Context().Err()
+		}
+
+		body, contentType, err := produce(f)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		_, err = w.Write(body)
+		return err
+	}()
+
+	var herr *httpError
+	switch {
+	case err == nil, r.Context().Err() != nil:
+		// done, or nobody is listening any more
+	case errors.As(err, &herr):
+		http.Error(w, herr.msg, herr.status)
+	default:
+		h.logf("rawserver: %s %s: %v", r.Method, r.URL, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) logf(format string, args ...any) {
+	if h.cfg.ErrorLog != nil {
+		h.cfg.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// intParam returns a numeric query parameter between 1 and maxSize.
+func intParam(r *http.Request, name string, def int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > maxSize {
+		return 0, errorf(http.StatusBadRequest, "invalid %s %q", name, s)
+	}
+	return n, nil
+}
+
+func (h *Handler) thumb(w http.ResponseWriter, r *http.Request) {
+	var size int
+	h.serve(w, r, func(f *file) (string, error) {
+		var err error
+		size, err = intParam(r, "size", h.cfg.ThumbSize)
+		return f.etag("thumb", size, h.opts), err
+	}, func(f *file) ([]byte, string, error) {
+		p := libraw.NewProcessor(h.opts).WithContext(r.Context())
+		preview, err := p.Preview(f.path, size)
+		if err != nil {
+			return nil, "", err
+		}
+		var buf bytes.Buffer
+		err = export.EncodeJPEG(&buf, export.Fit(preview.Image, size), &preview.Metadata, h.cfg.JPEGQuality)
+		return buf.Bytes(), "image/jpeg", err
+	})
+}
+
+func (h *Handler) render(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var opts libraw.ProcessorOptions
+	var width int
+	var format string
+	h.serve(w, r, func(f *file) (string, error) {
+		opts = h.opts
+		if name := q.Get("preset"); name != "" {
+			var ok bool
+			if opts, ok = libraw.LookupPreset(name); !ok {
+				return "", errorf(http.StatusBadRequest, "unknown preset %q", name)
+			}
+		}
+		var err error
+		if width, err = intParam(r, "w", 0); err != nil {
+			return "", err
+		}
+		switch format = q.Get("format"); format {
+		case "":
+			format = "jpeg"
+		case "jpeg", "png":
+		default:
+			return "", errorf(http.StatusBadRequest, "unknown format %q", format)
+		}
+		return f.etag("render", opts, width, format, h.cfg.JPEGQuality), nil
+	}, func(f *file) ([]byte, string, error) {
+		p := libraw.NewProcessor(opts).WithContext(r.Context())
+		if width > 0 {
+			// half size decoding is much faster and still large enough
+			info, err := p.Identify(f.path, false)
+			if err != nil {
+				return nil, "", err
+			}
+			if s := info.Metadata.Sizes; int(max(s.Width, s.Height))/2 >= width {
+				opts.HalfSize = true
+				p = libraw.NewProcessor(opts).WithContext(r.Context())
+			}
+		}
+
+		rendered, err := p.Render(f.path)
+		if err != nil {
+			return nil, "", err
+		}
+		img := export.Fit(rendered.Image, width)
+		var buf bytes.Buffer
+		if format == "png" {
+			err = export.EncodePNG(&buf, img, rendered.ICCProfile)
+			return buf.Bytes(), "image/png", err
+		}
+		err = export.EncodeJPEGWithOptions(&buf, img, &export.JPEGOptions{
+			Quality:    h.cfg.JPEGQuality,
+			ICCProfile: rendered.ICCProfile,
+			Metadata:   &rendered.Metadata,
+		})
+		return buf.Bytes(), "image/jpeg", err
+	})
+}
+
+func (h *Handler) meta(w http.ResponseWriter, r *http.Request) {
+	unpack := r.URL.Query().Get("unpack") == "1"
+	h.serve(w, r, func(f *file) (string, error) {
+		return f.etag("meta", unpack), nil
+	}, func(f *file) ([]byte, string, error) {
+		info, err := libraw.NewProcessor(h.opts).WithContext(r.Context()).Identify(f.path, unpack)
+		if err != nil {
+			return nil, "", err
+		}
+		body, err := json.Marshal(struct {
+			Path string `json:"path"`
+			*libraw.FileInfo
+		}{f.rel, info})
+		return body, "application/json", err
+	})
+}
@@ -0,0 +1,121 @@
+package rawserver
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestHandler(t *testing.T) (*Handler, string) {
+	t.Helper()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "day1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"root/day1/a.CR2", "secret.CR2"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("not really raw"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "secret.CR2"), filepath.Join(root, "link.CR2")); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+
+	h, err := New(Config{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, root
+}
+
+func get(h http.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRejectedRequests(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	for target, want := range map[string]int{
+		"/meta":                               http.StatusBadRequest,
+		"/meta?path=../secret.CR2":            http.StatusBadRequest,
+		"/meta?path=day1/../../secret.CR2":    http.StatusBadRequest,
+		"/meta?path=/../secret.CR2":           http.StatusBadRequest,
+		"/meta?path=link.CR2":                 http.StatusForbidden,
+		"/meta?path=day1/missing.CR2":         http.StatusNotFound,
+		"/meta?path=day1":                     http.StatusNotFound,
+		"/meta?path=day1/a.CR2/x":             http.StatusNotFound,
+		"/thumb?path=day1/a.CR2&size=0":       http.StatusBadRequest,
+		"/render?path=day1/a.CR2&w=huge":      http.StatusBadRequest,
+		"/render?path=day1/a.CR2&preset=nope": http.StatusBadRequest,
+		"/render?path=day1/a.CR2&format=gif":  http.StatusBadRequest,
+		"/nothing?path=day1/a.CR2":            http.StatusNotFound,
+	} {
+		if rec := get(h, target, nil); rec.Code != want {
+			t.Errorf("GET %s: status %d, want %d (%s)", target, rec.Code, want, rec.Body)
+		}
+	}
+}
+
+func TestInternalErrorsHidden(t *testing.T) {
+	h, root := newTestHandler(t)
+	var logged bytes.Buffer
+	h.cfg.ErrorLog = log.New(&logged, "", 0)
+	h.mux.HandleFunc("GET /fail", func(w http.ResponseWriter, r *http.Request) {
+		h.serve(w, r, func(f *file) (string, error) { return f.etag("fail"), nil }, func(f *file) ([]byte, string, error) {
+			return nil, "", fmt.Errorf("open %s: input/output error", f.path)
+		})
+	})
+
+	rec := get(h, "/fail?path=day1/a.CR2", nil)
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), root) {
+		t.Errorf("status %d, body %q", rec.Code, rec.Body)
+	}
+	if !strings.Contains(logged.String(), root) {
+		t.Errorf("error not logged: %q", logged.String())
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	h, root := newTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/render?path=day1/a.CR2&w=800", nil)
+	f, err := h.resolve(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := f.etag("render", h.opts, 800, "jpeg", h.cfg.JPEGQuality)
+
+	// a matching ETag is answered without decoding the file
+	rec := get(h, "/render?path=day1/a.CR2&w=800", http.Header{"If-None-Match": {`"x", ` + etag}})
+	if rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != etag {
+		t.Fatalf("status %d, ETag %s", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	// other options or a modified file change the tag
+	if other := f.etag("render", h.opts, 801, "jpeg", h.cfg.JPEGQuality); other == etag {
+		t.Error("ETag ignores the width")
+	}
+	if err := os.WriteFile(filepath.Join(root, "day1/a.CR2"), []byte("changed content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f2, err := h.resolve(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f2.etag("render", h.opts, 800, "jpeg", h.cfg.JPEGQuality) == etag {
+		t.Error("ETag ignores file changes")
+	}
+}
@@ -0,0 +1,120 @@
+package golibraw
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Names of the built-in presets, see LookupPreset.
+const (
+	PresetFastPreview   = "fast-preview"
+	PresetNeutralLinear = "neutral-linear"
+	PresetCameraLook    = "camera-look"
+	PresetArchival16    = "archival-16"
+)
+
+// FastPreviewOptions renders a half size 8-bit sRGB image with linear
+// interpolation and the camera white balance, several times faster than
+// the defaults. Good for thumbnails and culling.
+func FastPreviewOptions() ProcessorOptions {
+	opts := NewProcessorOptions()
+	opts.HalfSize = true
+	opts.UserQual = InterpolationLinear
+	opts.UseCameraWb = true
+	return opts
+}
+
+// NeutralLinearOptions renders a 16-bit image with linear sRGB primaries
+// and no tone curve, auto brightness or embedded camera matrix, so pixel
+// values are proportional to scene light. Intended for analysis and for
+// pipelines that apply their own look.
+func NeutralLinearOptions() ProcessorOptions {
+	opts := NewProcessorOptions()
+	opts.Gamm[0], opts.Gamm[1] = 1, 1
+	opts.NoAutoBright = true
+	opts.OutputBps = 16
+	opts.UseCameraWb = true
+	opts.UseCameraMatrix = CameraMatrixNever
+	opts.UserQual = InterpolationAHD
+	return opts
+}
+
+// CameraLookOptions follows the camera as closely as LibRaw can: the as
+// shot white balance, the color matrix embedded in the file and blended
+// highlights, rendered to 8-bit sRGB.
+func CameraLookOptions() ProcessorOptions {
+	opts := NewProcessorOptions()
+	opts.UseCameraWb = true
+	opts.UseCameraMatrix = CameraMatrixAlways
+	opts.Highlight = HighlightBlend
+	opts.UserQual = InterpolationAHD
+	return opts
+}
+
+// Archival16Options renders a full size linear 16-bit ProPhoto RGB master
+// with DCB interpolation. Highlights are left unclipped and brightness is
+// not adjusted, so no recorded data is thrown away.
+func Archival16Options() ProcessorOptions {
+	opts := NewProcessorOptions()
+	opts.OutputColor = ProPhotoRGB
+	opts.OutputBps = 16
+	opts.Gamm[0], opts.Gamm[1] = 1, 1
+	opts.NoAutoBright = true
+	opts.UseCameraWb = true
+	opts.Highlight = HighlightUnclip
+	opts.UserQual = InterpolationDCB
+	return opts
+}
+
+var presets = struct {
+	sync.RWMutex
+	m map[string]ProcessorOptions
+}{m: map[string]ProcessorOptions{
+	PresetFastPreview:   FastPreviewOptions(),
+	PresetNeutralLinear: NeutralLinearOptions(),
+	PresetCameraLook:    CameraLookOptions(),
+	PresetArchival16:    Archival16Options(),
+}}
+
+// RegisterPreset makes opts available under name, so renders can be
+// selected by name in configs. The options must be valid, and a name can
+// only be registered once.
+func RegisterPreset(name string, opts ProcessorOptions) error {
+	if name == "" {
+		return fmt.Errorf("preset name is empty")
+	}
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("preset %q: %w", name, err)
+	}
+
+	presets.Lock()
+	defer presets.Unlock()
+	if _, ok := presets.m[name]; ok {
+		return fmt.Errorf("preset %q already registered", name)
+	}
+	opts.Unpack = opts.Unpack.clone()
+	presets.m[name] = opts
+	return nil
+}
+
+// LookupPreset returns a copy of the options registered under name.
+func LookupPreset(name string) (ProcessorOptions, bool) {
+	presets.RLock()
+	defer presets.RUnlock()
+	opts, ok := presets.m[name]
+	opts.Unpack = opts.Unpack.clone()
+	return opts, ok
+}
+
+// Presets returns the sorted names of all registered presets.
+func Presets() []string {
+	presets.RLock()
+	defer presets.RUnlock()
+	names := make([]string, 0, len(presets.m))
+	for name := range presets.m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,43 @@
+package golibraw
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPresets(t *testing.T) {
+	for _, name := range []string{PresetFastPreview, PresetNeutralLinear, PresetCameraLook, PresetArchival16} {
+		opts, ok := LookupPreset(name)
+		if !ok {
+			t.Fatalf("preset %q not registered", name)
+		}
+		if err := opts.Validate(); err != nil {
+			t.Errorf("preset %q: %v", name, err)
+		}
+	}
+
+	opts, _ := LookupPreset(PresetFastPreview)
+	opts.HalfSize = false
+	if again, _ := LookupPreset(PresetFastPreview); !again.HalfSize {
+		t.Error("changing a looked up preset changed the registry")
+	}
+
+	custom := CameraLookOptions()
+	custom.OutputBps = 16
+	if err := RegisterPreset("test-camera-look-16", custom); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterPreset("test-camera-look-16", custom); err == nil {
+		t.Error("duplicate preset registered")
+	}
+	if err := RegisterPreset(PresetArchival16, custom); err == nil {
+		t.Error("built-in preset replaced")
+	}
+	custom.OutputBps = 12
+	if err := RegisterPreset("test-invalid", custom); err == nil {
+		t.Error("invalid preset registered")
+	}
+	if !slices.Contains(Presets(), "test-camera-look-16") || slices.Contains(Presets(), "test-invalid") {
+		t.Errorf("Presets() = %v", Presets())
+	}
+}
@@ -0,0 +1,182 @@
+package golibraw
+
+// #include "libraw/libraw.h"
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"slices"
+
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// ExtractThumbnailIndex extracts embedded preview i, an index into
+// FileInfo.Thumbnails. ExtractThumbnail returns the one LibRaw selects.
+func (p *Processor) ExtractThumbnailIndex(filepath string, i int) (*Thumbnail, error) {
+	if err := p.ctxErr(); err != nil {
+		return nil, err
+	}
+
+	proc := C.libraw_init(0)
+	if proc == nil {
+		return nil, fmt.Errorf("failed to initialize libraw")
+	}
+	defer func() {
+		C.libraw_recycle(proc)
+		C.libraw_close(proc)
+	}()
+
+	cFile := C.CString(filepath)
+	defer freeCString(cFile)
+
+	if err := librawErr(C.libraw_open_file(proc, cFile)); err != nil {
+		return nil, err
+	}
+	if err := p.ctxErr(); err != nil { // opening can take a while on slow storage
+		return nil, err
+	}
+	if i < 0 || i >= int(proc.thumbs_list.thumbcount) {
+		return nil, fmt.Errorf("thumbnail %d out of range, file has %d", i, int(proc.thumbs_list.thumbcount))
+	}
+	if err := librawErr(C.libraw_unpack_thumb_ex(proc, C.int(i))); err != nil {
+		return nil, err
+	}
+	return copyThumbnail(proc)
+}
+
+// Image decodes the thumbnail. Bitmaps yield the types of ProcessRaw,
+// JPEGs whatever image/jpeg returns. The pixels are as stored in the
+// file, not rotated.
+func (t *Thumbnail) Image() (image.Image, error) {
+	switch t.Format {
+	case ThumbJpeg:
+		return jpeg.Decode(bytes.NewReader(t.Data))
+	case ThumbBitmap:
+		if t.Colors == 1 {
+			return convertToGray(t.Data, int(t.Width), int(t.Height), int(t.Bits))
+		}
+		return ConvertToImage(t.Data, int(t.Width), int(t.Height), int(t.Bits))
+	}
+	return nil, fmt.Errorf("cannot decode thumbnail format %d", t.Format)
+}
+
+// Preview is an upright preview image of a RAW file.
+type Preview struct {
+	Image    image.Image
+	Metadata metadata.ImgMetadata
+
+	// Thumbnail is the embedded preview used, nil when the raw data was
+	// rendered instead.
+	Thumbnail *ThumbnailInfo
+}
+
+// Preview returns the largest embedded preview of a file that decodes and
+// measures at least minSize pixels on its longer side, rotated upright.
+// When there is none, the raw data is rendered at half size with the
+// options of p.
+func (p *Processor) Preview(filepath string, minSize int) (*Preview, error) {
+	info, err := p.Identify(filepath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]int, len(info.Thumbnails))
+	for i := range order {
+		order[i] = i
+	}
+	area := func(i int) int { return info.Thumbnails[i].Width * info.Thumbnails[i].Height }
+	slices.SortStableFunc(order, func(a, b int) int { return area(b) - area(a) })
+
+	for _, i := range order {
+		t := info.Thumbnails[i]
+		if max(t.Width, t.Height) < minSize {
+			break
+		}
+		thumb, err := p.ExtractThumbnailIndex(filepath, i)
+		if err := p.ctxErr(); err != nil {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+		img, err := thumb.Image()
+		if err != nil {
+			continue
+		}
+		return &Preview{
+			Image:     flipImage(img, info.Metadata.Sizes.Flip),
+			Metadata:  info.Metadata,
+			Thumbnail: &t,
+		}, nil
+	}
+
+	opts := p.options
+	opts.HalfSize = true
+	img, meta, err := p.derive(opts).ProcessRaw(filepath)
+	if err != nil {
+		return nil, err
+	}
+	return &Preview{Image: img, Metadata: meta}, nil
+}
+
+// flipImage applies a LibRaw flip code (see LibRawSizes.Flip) the way
+// LibRaw orients processed images. Gray, Gray16 and RGBA64 images keep
+// their type; everything else becomes an *image.RGBA.
+func flipImage(img image.Image, flip int) image.Image {
+	flip &= 7
+	if flip == 0 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	ow, oh := w, h
+	if flip&4 != 0 {
+		ow, oh = h, w
+	}
+	rect := image.Rect(0, 0, ow, oh)
+
+	var src, dst []uint8
+	var srcStride, dstStride, bpp int
+	var out image.Image
+	switch m := img.(type) {
+	case *image.Gray:
+		o := image.NewGray(rect)
+		src, srcStride, dst, dstStride, bpp, out = m.Pix[m.PixOffset(b.Min.X, b.Min.Y):], m.Stride, o.Pix, o.Stride, 1, o
+	case *image.Gray16:
+		o := image.NewGray16(rect)
+		src, srcStride, dst, dstStride, bpp, out = m.Pix[m.PixOffset(b.Min.X, b.Min.Y):], m.Stride, o.Pix, o.Stride, 2, o
+	case *image.RGBA64:
+		o := image.NewRGBA64(rect)
+		src, srcStride, dst, dstStride, bpp, out = m.Pix[m.PixOffset(b.Min.X, b.Min.Y):], m.Stride, o.Pix, o.Stride, 8, o
+	default:
+		rgba, ok := img.(*image.RGBA)
+		if !ok {
+			rgba = image.NewRGBA(b)
+			draw.Draw(rgba, b, img, b.Min, draw.Src)
+		}
+		o := image.NewRGBA(rect)
+		src, srcStride, dst, dstStride, bpp, out = rgba.Pix[rgba.PixOffset(b.Min.X, b.Min.Y):], rgba.Stride, o.Pix, o.Stride, 4, o
+	}
+
+	for y := range oh {
+		for x := range ow {
+			row, col := y, x
+			if flip&4 != 0 {
+				row, col = col, row
+			}
+			if flip&2 != 0 {
+				row = h - 1 - row
+			}
+			if flip&1 != 0 {
+				col = w - 1 - col
+			}
+			copy(dst[y*dstStride+x*bpp:][:bpp], src[row*srcStride+col*bpp:])
+		}
+	}
+	return out
+}
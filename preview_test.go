@@ -0,0 +1,66 @@
+package golibraw
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	p := NewProcessor(NewProcessorOptions())
+
+	for _, path := range getAllFilesInTestDir() {
+		preview, err := p.Preview(path, 0)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if preview.Image.Bounds().Empty() {
+			t.Errorf("%s: empty preview", path)
+		}
+
+		// too large a minimum forces a render
+		rendered, err := p.Preview(path, 1<<20)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if rendered.Thumbnail != nil {
+			t.Errorf("%s: embedded preview used despite minSize", path)
+		}
+	}
+}
+
+func TestFlipImage(t *testing.T) {
+	// 3x2 image with distinct pixels, a b c / d e f
+	src := image.NewGray(image.Rect(0, 0, 3, 2))
+	copy(src.Pix, "abcdef")
+
+	for flip, want := range map[int]string{
+		int(FlipNone):       "abcdef",
+		int(FlipHorizontal): "cbafed",
+		int(FlipVertical):   "defabc",
+		int(FlipRotate180):  "fedcba",
+		int(FlipTranspose):  "adbecf",
+		int(FlipRotate270):  "cfbead",
+		int(FlipRotate90):   "daebfc",
+		int(FlipTransverse): "fcebda",
+	} {
+		got := flipImage(src, flip).(*image.Gray)
+		if string(got.Pix) != want {
+			t.Errorf("flip %d: %q, want %q", flip, got.Pix, want)
+		}
+		if flip&4 != 0 && got.Rect.Dx() != 2 {
+			t.Errorf("flip %d: size %v", flip, got.Rect)
+		}
+	}
+
+	// other image types become RGBA, sub-images keep their offset
+	ycc := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	ycc.Set(1, 1, color.NRGBA{255, 0, 0, 255})
+	sub := ycc.SubImage(image.Rect(1, 1, 3, 3))
+	got := flipImage(sub, int(FlipRotate180)).(*image.RGBA)
+	if c := got.RGBAAt(1, 1); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("rotated sub-image pixel %v", c)
+	}
+}
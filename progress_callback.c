@@ -0,0 +1,20 @@
+// Progress callback for LibRaw. LibRaw calls it between processing stages
+// and cancels the call when it returns non-zero.
+
+#include "libraw/libraw.h"
+#include "_cgo_export.h"
+
+#include <stdint.h>
+
+static int gorawProgressCallback(void *data, enum LibRaw_progress stage, int iteration, int expected)
+{
+  (void)stage;
+  (void)iteration;
+  (void)expected;
+  return goLibrawCancelled((uintptr_t)data);
+}
+
+void gorawSetProgressHandler(libraw_data_t *lr, uintptr_t handle)
+{
+  libraw_set_progress_handler(lr, handle ? gorawProgressCallback : NULL, (void *)handle);
+}
@@ -0,0 +1,188 @@
+package golibraw
+
+// #include "libraw/libraw.h"
+// #include <stdlib.h>
+import "C"
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"unsafe"
+
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// RawImage holds the unpacked sensor data of a RAW file before any
+// processing: no black subtraction, scaling, interpolation or rotation.
+type RawImage struct {
+	Width  int      // full sensor width, including masked pixels
+	Height int      // full sensor height, including masked pixels
+	Pix    []uint16 // Width*Height CFA samples, row major
+
+	Visible image.Rectangle // active area within the full frame
+	Filters uint32          // LibRaw CFA bitmask, 9 for X-Trans, 0 for none
+	XTrans  [6][6]uint8     // X-Trans layout, relative to the visible area
+}
+
+// CFAColor returns the color index (into the camera's color description,
+// e.g. "RGBG") of the pixel at row, col relative to the visible area, or -1
+// when the sensor has no color filter array.
+func (r *RawImage) CFAColor(row, col int) int {
+	switch {
+	case r.Filters == 0:
+		return -1
+	case r.Filters == 9:
+		return int(r.XTrans[(row+6)%6][(col+6)%6])
+	case r.Filters < 1000:
+		// Leaf CatchLight style 16x16 patterns are not exposed by LibRaw's C API.
+		return -1
+	}
+	return int(r.Filters >> ((((row << 1) & 14) | (col & 1)) << 1) & 3)
+}
+
+// Image returns the visible area as a 16-bit grayscale image. Written with
+// export.EncodePGM it matches the output of `dcraw_emu -D -4 -j -t 0`.
+func (r *RawImage) Image() *image.Gray16 {
+	v := r.Visible
+	img := image.NewGray16(image.Rect(0, 0, v.Dx(), v.Dy()))
+	for y := range v.Dy() {
+		src := r.Pix[(v.Min.Y+y)*r.Width+v.Min.X:]
+		dst := img.Pix[y*img.Stride:]
+		for x := range v.Dx() {
+			binary.BigEndian.PutUint16(dst[2*x:], src[x])
+		}
+	}
+	return img
+}
+
+// UnpackRaw opens and unpacks a RAW file and returns its CFA data without
+// processing it. Files whose data is not a single channel mosaic (Foveon,
+// sRAW, linear DNG) are rejected.
+func (p *Processor) UnpackRaw(filepath string) (*RawImage, metadata.ImgMetadata, error) {
+	if p.invalid != nil {
+		return nil, metadata.ImgMetadata{}, p.invalid
+	}
+	if err := p.ctxErr(); err != nil {
+		return nil, metadata.ImgMetadata{}, err
+	}
+
+	proc := C.libraw_init(0)
+	if proc == nil {
+		return nil, metadata.ImgMetadata{}, fmt.Errorf("failed to initialize libraw")
+	}
+	defer func() {
+		C.libraw_recycle(proc)
+		C.libraw_close(proc)
+	}()
+
+	proc.params = p.options.Apply(proc.params)
+	defer p.options.Free(proc.params)
+	proc.rawparams = p.options.Unpack.Apply(proc.rawparams)
+	defer p.options.Unpack.Free(proc.rawparams)
+	watch := p.watchContext(proc)
+	defer watch.stop()
+
+	cFile := C.CString(filepath)
+	defer freeCString(cFile)
+
+	collector := p.collectExifTags(proc)
+	err := librawErr(C.libraw_open_file(proc, cFile))
+	exifTags := collector.stop()
+	if err != nil {
+		return nil, metadata.ImgMetadata{}, err
+	}
+
+	if err := watch.err(librawErr(C.libraw_unpack(proc))); err != nil {
+		return nil, metadata.ImgMetadata{}, err
+	}
+
+	raw, err := copyRawImage(proc)
+	if err != nil {
+		return nil, metadata.ImgMetadata{}, err
+	}
+	meta := readMetadata(proc)
+	meta.ExifTags = exifTags
+	return raw, meta, nil
+}
+
+// copyRawImage copies the CFA buffer of an unpacked file into Go memory.
+func copyRawImage(proc *C.libraw_data_t) (*RawImage, error) {
+	if proc.rawdata.raw_image == nil {
+		return nil, fmt.Errorf("libraw: file has no single channel CFA data")
+	}
+
+	sizes := proc.rawdata.sizes
+	width, height := int(sizes.raw_width), int(sizes.raw_height)
+	pitch := int(sizes.raw_pitch) / 2
+	src := unsafe.Slice((*uint16)(unsafe.Pointer(proc.rawdata.raw_image)), pitch*height)
+
+	raw := &RawImage{
+		Width:  width,
+		Height: height,
+		Pix:    make([]uint16, width*height),
+		Visible: image.Rect(
+			int(sizes.left_margin), int(sizes.top_margin),
+			int(sizes.left_margin)+int(sizes.width), int(sizes.top_margin)+int(sizes.height),
+		),
+		Filters: uint32(proc.rawdata.iparams.filters),
+	}
+	for row := range height {
+		copy(raw.Pix[row*width:(row+1)*width], src[row*pitch:])
+	}
+	for i := range 6 {
+		for j := range 6 {
+			raw.XTrans[i][j] = uint8(proc.rawdata.iparams.xtrans[i][j])
+		}
+	}
+	return raw, nil
+}
+
+// ProcessToFile processes a RAW file and writes the result with LibRaw's
+// own writer, libraw_dcraw_ppm_tiff_writer: a PPM (PGM for single channel
+// output), or a TIFF when OutputTiff is set. This is the code path used by
+// dcraw_emu, so the files can serve as a reference for ProcessRaw output.
+func (p *Processor) ProcessToFile(filepath, outPath string) error {
+	if p.invalid != nil {
+		return p.invalid
+	}
+	if err := p.ctxErr(); err != nil {
+		return err
+	}
+
+	proc := C.libraw_init(0)
+	if proc == nil {
+		return fmt.Errorf("failed to initialize libraw")
+	}
+	defer func() {
+		C.libraw_recycle(proc)
+		C.libraw_close(proc)
+	}()
+
+	proc.params = p.options.Apply(proc.params)
+	defer p.options.Free(proc.params)
+	proc.rawparams = p.options.Unpack.Apply(proc.rawparams)
+	defer p.options.Unpack.Free(proc.rawparams)
+	watch := p.watchContext(proc)
+	defer watch.stop()
+
+	cFile := C.CString(filepath)
+	defer freeCString(cFile)
+
+	if err := librawErr(C.libraw_open_file(proc, cFile)); err != nil {
+		return err
+	}
+
+	if err := watch.err(librawErr(C.libraw_unpack(proc))); err != nil {
+		return err
+	}
+
+	if err := watch.err(librawErr(C.libraw_dcraw_process(proc))); err != nil {
+		return err
+	}
+
+	cOut := C.CString(outPath)
+	defer freeCString(cOut)
+
+	return librawErr(C.libraw_dcraw_ppm_tiff_writer(proc, cOut))
+}
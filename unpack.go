@@ -0,0 +1,176 @@
+package golibraw
+
+// #include "libraw/libraw.h"
+// #include <stdlib.h>
+import "C"
+
+import (
+	"slices"
+	"sync"
+	"unsafe"
+)
+
+// DNGSDKFlags selects the DNG flavours decoded with the Adobe DNG SDK
+// instead of LibRaw's own decoder. It only has an effect if LibRaw was
+// built with the SDK, see CapDNGSDK.
+type DNGSDKFlags int
+
+const (
+	DNGSDKNone    DNGSDKFlags = 0  // LIBRAW_DNG_NONE
+	DNGSDKFloat   DNGSDKFlags = 1  // LIBRAW_DNG_FLOAT, floating point data
+	DNGSDKLinear  DNGSDKFlags = 2  // LIBRAW_DNG_LINEAR, demosaiced data
+	DNGSDKDeflate DNGSDKFlags = 4  // LIBRAW_DNG_DEFLATE, deflate compressed
+	DNGSDKXTrans  DNGSDKFlags = 8  // LIBRAW_DNG_XTRANS, Fujifilm X-Trans mosaics
+	DNGSDKOther   DNGSDKFlags = 16 // LIBRAW_DNG_OTHER, other non-Bayer mosaics
+	DNGSDK8Bit    DNGSDKFlags = 32 // LIBRAW_DNG_8BIT, 8-bit data
+
+	DNGSDKDefault = DNGSDKFloat | DNGSDKLinear | DNGSDKDeflate | DNGSDK8Bit // LIBRAW_DNG_DEFAULT
+	DNGSDKAll     = DNGSDKDefault | DNGSDKXTrans | DNGSDKOther              // LIBRAW_DNG_ALL
+)
+
+// RawOptions are the raw processing flags of libraw_raw_unpack_params_t.
+type RawOptions uint
+
+const (
+	RawPentaxPSAllFrames             RawOptions = 1 << 0  // LIBRAW_RAWOPTIONS_PENTAX_PS_ALLFRAMES, decode all Pentax pixel shift frames
+	RawConvertFloatToInt             RawOptions = 1 << 1  // LIBRAW_RAWOPTIONS_CONVERTFLOAT_TO_INT, scale floating point DNG to integers
+	RawARQSkipChannelSwap            RawOptions = 1 << 2  // LIBRAW_RAWOPTIONS_ARQ_SKIP_CHANNEL_SWAP
+	RawNoRotateForKodakThumbnails    RawOptions = 1 << 3  // LIBRAW_RAWOPTIONS_NO_ROTATE_FOR_KODAK_THUMBNAILS
+	RawUsePPM16Thumbs                RawOptions = 1 << 5  // LIBRAW_RAWOPTIONS_USE_PPM16_THUMBS
+	RawDontCheckDNGIlluminant        RawOptions = 1 << 6  // LIBRAW_RAWOPTIONS_DONT_CHECK_DNG_ILLUMINANT
+	RawDNGSDKZeroCopy                RawOptions = 1 << 7  // LIBRAW_RAWOPTIONS_DNGSDK_ZEROCOPY
+	RawZeroFiltersForMonochromeTIFF  RawOptions = 1 << 8  // LIBRAW_RAWOPTIONS_ZEROFILTERS_FOR_MONOCHROMETIFFS
+	RawDNGAddEnhanced                RawOptions = 1 << 9  // LIBRAW_RAWOPTIONS_DNG_ADD_ENHANCED, list enhanced DNG images as extra frames
+	RawDNGAddPreviews                RawOptions = 1 << 10 // LIBRAW_RAWOPTIONS_DNG_ADD_PREVIEWS
+	RawDNGPreferLargestImage         RawOptions = 1 << 11 // LIBRAW_RAWOPTIONS_DNG_PREFER_LARGEST_IMAGE
+	RawDNGStage2                     RawOptions = 1 << 12 // LIBRAW_RAWOPTIONS_DNG_STAGE2, DNG SDK processing up to stage 2
+	RawDNGStage3                     RawOptions = 1 << 13 // LIBRAW_RAWOPTIONS_DNG_STAGE3, DNG SDK processing up to stage 3
+	RawDNGAllowSizeChange            RawOptions = 1 << 14 // LIBRAW_RAWOPTIONS_DNG_ALLOWSIZECHANGE
+	RawDNGDisableWBAdjust            RawOptions = 1 << 15 // LIBRAW_RAWOPTIONS_DNG_DISABLEWBADJUST
+	RawProvideNonstandardWB          RawOptions = 1 << 16 // LIBRAW_RAWOPTIONS_PROVIDE_NONSTANDARD_WB
+	RawCameraWBFallbackToDaylight    RawOptions = 1 << 17 // LIBRAW_RAWOPTIONS_CAMERAWB_FALLBACK_TO_DAYLIGHT
+	RawCheckThumbnailsKnownVendors   RawOptions = 1 << 18 // LIBRAW_RAWOPTIONS_CHECK_THUMBNAILS_KNOWN_VENDORS
+	RawCheckThumbnailsAllVendors     RawOptions = 1 << 19 // LIBRAW_RAWOPTIONS_CHECK_THUMBNAILS_ALL_VENDORS
+	RawDNGStage2IfPresent            RawOptions = 1 << 20 // LIBRAW_RAWOPTIONS_DNG_STAGE2_IFPRESENT
+	RawDNGStage3IfPresent            RawOptions = 1 << 21 // LIBRAW_RAWOPTIONS_DNG_STAGE3_IFPRESENT
+	RawDNGAddMasks                   RawOptions = 1 << 22 // LIBRAW_RAWOPTIONS_DNG_ADD_MASKS
+	RawCanonIgnoreMakernotesRotation RawOptions = 1 << 23 // LIBRAW_RAWOPTIONS_CANON_IGNORE_MAKERNOTES_ROTATION
+)
+
+// UnpackOptions control how LibRaw opens and decodes a file, before any
+// rendering happens (libraw_raw_unpack_params_t). The zero value keeps
+// all of LibRaw's defaults; NewProcessorOptions fills in the defaults
+// (see DefaultUnpackOptions) so fields can be changed one by one.
+type UnpackOptions struct {
+	ShotSelect     uint        // frame to decode in files with several images, see ImgMetadata.IData.RawCount
+	UseRawSpeed    bool        // decode with RawSpeed, if LibRaw was built with it (CapRawSpeed)
+	UseDNGSDK      DNGSDKFlags // DNG flavours decoded with the Adobe DNG SDK
+	Options        RawOptions
+	MaxRawMemoryMB uint // files needing more memory for raw data are rejected
+
+	SonyARW2PosterizationThr int     // posterization threshold for Sony ARW2 files, 0 = off
+	CoolscanNEFGamma         float32 // gamma of Nikon Coolscan NEF scans
+	P4ShotOrder              string  // order of the four Pentax pixel shift frames, e.g. "3102"
+
+	// CustomCameras adds cameras LibRaw does not know, one per string in
+	// the format of dcraw_emu -camera: "fsize,rw,rh,lm,tm,rm,bm,lf,cf,max,flags,make,model,offset".
+	CustomCameras []string
+}
+
+var defaultUnpackOptions = sync.OnceValue(func() UnpackOptions {
+	proc := C.libraw_init(0)
+	if proc == nil {
+		return UnpackOptions{}
+	}
+	defer C.libraw_close(proc)
+	return newUnpackOptions(&proc.rawparams)
+})
+
+// DefaultUnpackOptions returns the defaults of the linked LibRaw.
+func DefaultUnpackOptions() UnpackOptions {
+	return defaultUnpackOptions()
+}
+
+func newUnpackOptions(params *C.libraw_raw_unpack_params_t) UnpackOptions {
+	u := UnpackOptions{
+		ShotSelect:     uint(params.shot_select),
+		UseRawSpeed:    params.use_rawspeed != 0,
+		UseDNGSDK:      DNGSDKFlags(params.use_dngsdk),
+		Options:        RawOptions(params.options),
+		MaxRawMemoryMB: uint(params.max_raw_memory_mb),
+
+		SonyARW2PosterizationThr: int(params.sony_arw2_posterization_thr),
+		CoolscanNEFGamma:         float32(params.coolscan_nef_gamma),
+		P4ShotOrder:              C.GoString(&params.p4shot_order[0]),
+	}
+	if params.custom_camera_strings != nil {
+		for _, s := range unsafe.Slice(params.custom_camera_strings, 1<<16) {
+			if s == nil {
+				break
+			}
+			u.CustomCameras = append(u.CustomCameras, C.GoString(s))
+		}
+	}
+	return u
+}
+
+// IsZero reports whether u is the zero value, which leaves LibRaw's
+// defaults untouched.
+func (u *UnpackOptions) IsZero() bool {
+	return u.ShotSelect == 0 && !u.UseRawSpeed && u.UseDNGSDK == 0 && u.Options == 0 &&
+		u.MaxRawMemoryMB == 0 && u.SonyARW2PosterizationThr == 0 && u.CoolscanNEFGamma == 0 &&
+		u.P4ShotOrder == "" && len(u.CustomCameras) == 0
+}
+
+func (u *UnpackOptions) clone() UnpackOptions {
+	c := *u
+	c.CustomCameras = slices.Clone(u.CustomCameras)
+	return c
+}
+
+// Apply copies the options into params, unless u is the zero value.
+// Custom camera strings are allocated in C memory, release them with Free.
+func (u *UnpackOptions) Apply(params C.libraw_raw_unpack_params_t) C.libraw_raw_unpack_params_t {
+	if u.IsZero() {
+		return params
+	}
+
+	params.shot_select = C.uint(u.ShotSelect)
+	params.use_rawspeed = 0
+	if u.UseRawSpeed {
+		params.use_rawspeed = 1
+	}
+	params.use_dngsdk = C.int(u.UseDNGSDK)
+	params.options = C.uint(u.Options)
+	params.max_raw_memory_mb = C.uint(u.MaxRawMemoryMB)
+	params.sony_arw2_posterization_thr = C.int(u.SonyARW2PosterizationThr)
+	params.coolscan_nef_gamma = C.float(u.CoolscanNEFGamma)
+
+	params.p4shot_order = [5]C.char{}
+	for i := 0; i < len(u.P4ShotOrder) && i < 4; i++ {
+		params.p4shot_order[i] = C.char(u.P4ShotOrder[i])
+	}
+
+	params.custom_camera_strings = nil
+	if len(u.CustomCameras) > 0 {
+		n := len(u.CustomCameras) + 1
+		list := (**C.char)(C.calloc(C.size_t(n), C.size_t(unsafe.Sizeof((*C.char)(nil)))))
+		for i, s := range u.CustomCameras {
+			unsafe.Slice(list, n)[i] = C.CString(s)
+		}
+		params.custom_camera_strings = list
+	}
+
+	return params
+}
+
+// Free releases the custom camera strings allocated by Apply.
+func (u *UnpackOptions) Free(params C.libraw_raw_unpack_params_t) {
+	if u.IsZero() || params.custom_camera_strings == nil {
+		return
+	}
+	for _, s := range unsafe.Slice(params.custom_camera_strings, len(u.CustomCameras)) {
+		C.free(unsafe.Pointer(s))
+	}
+	C.free(unsafe.Pointer(params.custom_camera_strings))
+}
@@ -0,0 +1,30 @@
+package golibraw
+
+import "testing"
+
+func TestDefaultUnpackOptions(t *testing.T) {
+	u := DefaultUnpackOptions()
+	if u.IsZero() || u.MaxRawMemoryMB == 0 || u.P4ShotOrder != "3102" {
+		t.Fatalf("unexpected LibRaw defaults %+v", u)
+	}
+	opts := NewProcessorOptions()
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Unpack.P4ShotOrder = "0012"
+	opts.Unpack.MaxRawMemoryMB = 0
+	if err := opts.Validate(); err == nil {
+		t.Error("invalid unpack options accepted")
+	}
+}
+
+func TestShotSelectOutOfRange(t *testing.T) {
+	opts := NewProcessorOptions()
+	opts.Unpack.ShotSelect = 99
+	for _, path := range getAllFilesInTestDir() {
+		if _, _, err := NewProcessor(opts).ProcessRaw(path); err == nil {
+			t.Errorf("%s: frame 99 decoded", path)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package golibraw
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+)
+
+// OptionError describes a ProcessorOptions field with an invalid value.
+type OptionError struct {
+	Field      string
+	Value      any
+	Constraint string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("ProcessorOptions.%s = %v: %s", e.Field, e.Value, e.Constraint)
+}
+
+// Validate checks every field against the range LibRaw accepts and returns
+// an error listing all invalid fields (see errors.Join), each an
+// *OptionError. Profile, bad pixel and dark frame paths must exist.
+//
+// Processing methods call Validate before handing the options to LibRaw.
+func (opts *ProcessorOptions) Validate() error {
+	var errs []error
+	check := func(ok bool, field string, value any, constraint string) {
+		if !ok {
+			errs = append(errs, &OptionError{field, value, constraint})
+		}
+	}
+	finite := func(vs ...float64) bool {
+		for _, v := range vs {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return false
+			}
+		}
+		return true
+	}
+	box := func(field string, b Box) {
+		check(b.IsEmpty() || (b.X2 > 0 && b.Y2 > 0), field, b, "width (X2) and height (Y2) must be positive")
+	}
+	file := func(field, path string) {
+		if path == "" {
+			return
+		}
+		_, err := os.Stat(path)
+		check(err == nil, field, path, "file not found")
+	}
+
+	box("Greybox", opts.Greybox)
+	box("Cropbox", opts.Cropbox)
+
+	check(finite(opts.Aber[:]...) && opts.Aber[0] > 0 && opts.Aber[2] > 0, "Aber", opts.Aber,
+		"red (Aber[0]) and blue (Aber[2]) scale factors must be positive")
+	check(finite(opts.Gamm[:]...) && opts.Gamm[0] >= 0 && opts.Gamm[1] >= 0, "Gamm", opts.Gamm,
+		"power (Gamm[0]) and toe slope (Gamm[1]) must not be negative")
+	var mul []float64
+	for _, m := range opts.UserMul {
+		mul = append(mul, float64(m))
+	}
+	check(finite(mul...) && min(mul[0], mul[1], mul[2], mul[3]) >= 0, "UserMul", opts.UserMul,
+		"multipliers must not be negative")
+	check(finite(float64(opts.Bright)) && opts.Bright > 0, "Bright", opts.Bright, "must be positive")
+	check(finite(float64(opts.Threshold)) && opts.Threshold >= 0, "Threshold", opts.Threshold, "must not be negative")
+
+	check(knownEnum(opts.Highlight, highlightNames), "Highlight", int(opts.Highlight),
+		enumConstraint(highlightNames))
+	check(knownEnum(opts.UseCameraMatrix, cameraMatrixNames), "UseCameraMatrix", int(opts.UseCameraMatrix),
+		enumConstraint(cameraMatrixNames))
+	check(knownEnum(opts.OutputColor, outputColorNames), "OutputColor", int(opts.OutputColor),
+		enumConstraint(outputColorNames))
+
+	if opts.CameraProfile != "embed" {
+		file("CameraProfile", opts.CameraProfile)
+	}
+	file("OutputProfile", opts.OutputProfile)
+	file("BadPixels", opts.BadPixels)
+	file("DarkFrame", opts.DarkFrame)
+
+	check(opts.OutputBps == 8 || opts.OutputBps == 16, "OutputBps", opts.OutputBps, "must be 8 or 16")
+	check(opts.OutputFlags >= 0, "OutputFlags", opts.OutputFlags, "must not be negative")
+	check(knownEnum(opts.UserFlip, flipNames), "UserFlip", int(opts.UserFlip), enumConstraint(flipNames))
+	check(knownEnum(opts.UserQual, interpolationNames), "UserQual", int(opts.UserQual),
+		enumConstraint(interpolationNames))
+	check(opts.UserBlack >= -1, "UserBlack", opts.UserBlack, "must be -1 (from file) or a black level")
+	check(opts.UserSat == -1 || opts.UserSat > 0, "UserSat", opts.UserSat, "must be -1 (from file) or a positive white level")
+	check(opts.MedPasses >= 0, "MedPasses", opts.MedPasses, "must not be negative")
+	check(opts.AutoBrightThr >= 0 && opts.AutoBrightThr < 1, "AutoBrightThr", opts.AutoBrightThr,
+		"must be a fraction of pixels in [0, 1)")
+	check(opts.AdjustMaximumThr >= 0 && opts.AdjustMaximumThr <= 1, "AdjustMaximumThr", opts.AdjustMaximumThr,
+		"must be 0 (disabled) or a fraction up to 1")
+	check(opts.DcbIterations >= -1, "DcbIterations", opts.DcbIterations, "must be -1 (default) or a number of iterations")
+	check(knownEnum(opts.FbddNoiserd, fbddNames), "FbddNoiserd", int(opts.FbddNoiserd),
+		enumConstraint(fbddNames))
+	if opts.ExpCorrect {
+		check(opts.ExpShift >= 0.25 && opts.ExpShift <= 8, "ExpShift", opts.ExpShift,
+			"must be a linear factor from 0.25 (-2 EV) to 8 (+3 EV)")
+		check(opts.ExpPreser >= 0 && opts.ExpPreser <= 1, "ExpPreser", opts.ExpPreser, "must be 0 to 1")
+	}
+
+	if u := &opts.Unpack; !u.IsZero() {
+		check(u.MaxRawMemoryMB > 0, "Unpack.MaxRawMemoryMB", u.MaxRawMemoryMB,
+			"must be positive, start from DefaultUnpackOptions")
+		check(u.CoolscanNEFGamma > 0, "Unpack.CoolscanNEFGamma", u.CoolscanNEFGamma, "must be positive")
+		order := []byte(u.P4ShotOrder)
+		slices.Sort(order)
+		check(string(order) == "0123", "Unpack.P4ShotOrder", u.P4ShotOrder,
+			"must be an order of the frames 0, 1, 2 and 3, e.g. \"3102\"")
+		check(u.UseDNGSDK >= 0 && u.UseDNGSDK&^DNGSDKAll == 0, "Unpack.UseDNGSDK", u.UseDNGSDK,
+			"must be a combination of the DNGSDK flags")
+	}
+
+	return errors.Join(errs...)
+}
+
+func knownEnum[T ~int | ~uint8](v T, names []enumName[T]) bool {
+	_, ok := lookupEnum(v, names)
+	return ok
+}
+
+// enumConstraint lists the values of an enum, e.g. "must be 0 (off), 1 (light) or 2 (full)".
+func enumConstraint[T ~int | ~uint8](names []enumName[T]) string {
+	var b strings.Builder
+	b.WriteString("must be ")
+	for i, n := range names {
+		switch {
+		case i == len(names)-1:
+			b.WriteString(" or ")
+		case i > 0:
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%d (%s)", int(n.v), n.name)
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package golibraw
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	opts := NewProcessorOptions()
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("default options are invalid: %v", err)
+	}
+
+	opts.Highlight = 42
+	opts.OutputBps = 12
+	opts.UserQual = 99
+	opts.OutputColor = 9
+	opts.Cropbox = Box{X1: 10, Y1: 10}
+	opts.DarkFrame = "testdata/does-not-exist.pgm"
+
+	err := opts.Validate()
+	if err == nil {
+		t.Fatal("invalid options accepted")
+	}
+
+	var fields []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var oe *OptionError
+		if !errors.As(e, &oe) {
+			t.Fatalf("error %v is not an *OptionError", e)
+		}
+		fields = append(fields, oe.Field)
+	}
+	want := []string{"Cropbox", "Highlight", "OutputColor", "DarkFrame", "OutputBps", "UserQual"}
+	if len(fields) != len(want) {
+		t.Fatalf("invalid fields = %v, want %v", fields, want)
+	}
+	for i := range want {
+		if fields[i] != want[i] {
+			t.Errorf("invalid fields = %v, want %v", fields, want)
+			break
+		}
+	}
+
+	if _, _, err := NewProcessor(opts).ProcessRaw("unused.NEF"); err == nil {
+		t.Error("ProcessRaw ran with invalid options")
+	}
+}
+
+func TestValidateEdges(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		set   func(*ProcessorOptions)
+		valid bool
+	}{
+		{"DcbIterations default", func(o *ProcessorOptions) { o.DcbIterations = -1 }, true},
+		{"DcbIterations", func(o *ProcessorOptions) { o.DcbIterations = 4 }, true},
+		{"DcbIterations below -1", func(o *ProcessorOptions) { o.DcbIterations = -2 }, false},
+		{"UseCameraMatrix always", func(o *ProcessorOptions) { o.UseCameraMatrix = CameraMatrixAlways }, true},
+		{"UseCameraMatrix 2", func(o *ProcessorOptions) { o.UseCameraMatrix = 2 }, false},
+	} {
+		opts := NewProcessorOptions()
+		tc.set(&opts)
+		if err := opts.Validate(); (err == nil) != tc.valid {
+			t.Errorf("%s: Validate() = %v", tc.name, err)
+		}
+	}
+}
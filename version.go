@@ -0,0 +1,155 @@
+package golibraw
+
+// #include "libraw/libraw.h"
+//
+// // the raw unpack parameters (UnpackOptions) and the thumbnail list are
+// // only in LibRaw 0.21 and later
+// #if !LIBRAW_COMPILE_CHECK_VERSION_NOTLESS(0, 21)
+// #error "go-libraw needs LibRaw 0.21 or newer"
+// #endif
+import "C"
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// LibRawVersion is a LibRaw version number as built by LIBRAW_MAKE_VERSION:
+// (major << 16) | (minor << 8) | patch.
+type LibRawVersion int
+
+func (v LibRawVersion) Major() int { return int(v) >> 16 }
+func (v LibRawVersion) Minor() int { return int(v) >> 8 & 0xff }
+func (v LibRawVersion) Patch() int { return int(v) & 0xff }
+
+func (v LibRawVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+}
+
+// MakeVersion builds a LibRawVersion for comparisons, e.g.
+// VersionNumber() >= MakeVersion(0, 21, 0).
+func MakeVersion(major, minor, patch int) LibRawVersion {
+	return LibRawVersion(major<<16 | minor<<8 | patch)
+}
+
+// Version returns the version string of the linked LibRaw, e.g.
+// "0.21.2-Release".
+func Version() string {
+	return C.GoString(C.libraw_version())
+}
+
+// VersionNumber returns the version of the linked LibRaw.
+func VersionNumber() LibRawVersion {
+	return LibRawVersion(C.libraw_versionNumber())
+}
+
+// Capabilities are the optional features LibRaw was built with.
+type Capabilities uint
+
+const (
+	CapRawSpeed     Capabilities = 1 << 0 // LIBRAW_CAPS_RAWSPEED
+	CapDNGSDK       Capabilities = 1 << 1 // LIBRAW_CAPS_DNGSDK
+	CapGPRSDK       Capabilities = 1 << 2 // LIBRAW_CAPS_GPRSDK
+	CapUnicodePaths Capabilities = 1 << 3 // LIBRAW_CAPS_UNICODEPATHS
+	CapX3FTools     Capabilities = 1 << 4 // LIBRAW_CAPS_X3FTOOLS
+	CapRPi6by9      Capabilities = 1 << 5 // LIBRAW_CAPS_RPI6BY9
+	CapZlib         Capabilities = 1 << 6 // LIBRAW_CAPS_ZLIB, deflate compressed DNG
+	CapJPEG         Capabilities = 1 << 7 // LIBRAW_CAPS_JPEG, lossy DNG and some thumbnails
+	CapRawSpeed3    Capabilities = 1 << 8 // LIBRAW_CAPS_RAWSPEED3
+	CapRawSpeedBits Capabilities = 1 << 9 // LIBRAW_CAPS_RAWSPEED_BITS
+)
+
+var capabilityNames = []struct {
+	c    Capabilities
+	name string
+}{
+	{CapRawSpeed, "RawSpeed"},
+	{CapDNGSDK, "DNG SDK"},
+	{CapGPRSDK, "GPR SDK"},
+	{CapUnicodePaths, "Unicode paths"},
+	{CapX3FTools, "X3F tools"},
+	{CapRPi6by9, "RPi 6by9"},
+	{CapZlib, "zlib"},
+	{CapJPEG, "JPEG"},
+	{CapRawSpeed3, "RawSpeed3"},
+	{CapRawSpeedBits, "RawSpeed bits"},
+}
+
+// GetCapabilities returns the features of the linked LibRaw.
+func GetCapabilities() Capabilities {
+	return Capabilities(C.libraw_capabilities())
+}
+
+// Has reports whether all capabilities in c2 are present.
+func (c Capabilities) Has(c2 Capabilities) bool {
+	return c&c2 == c2
+}
+
+// String lists the capabilities by name, e.g. "zlib, JPEG".
+func (c Capabilities) String() string {
+	var names []string
+	for _, n := range capabilityNames {
+		if c.Has(n.c) {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// CameraCount returns the number of camera models LibRaw supports.
+func CameraCount() int {
+	return int(C.libraw_cameraCount())
+}
+
+var cameraList = sync.OnceValue(func() []string {
+	n := CameraCount()
+	list := unsafe.Slice(C.libraw_cameraList(), n+1)
+	cameras := make([]string, 0, n)
+	for _, name := range list {
+		if name == nil {
+			break
+		}
+		cameras = append(cameras, C.GoString(name))
+	}
+	return cameras
+})
+
+// CameraList returns the names ("Make Model") of the cameras LibRaw
+// supports. Some entries carry a variant in parentheses, e.g.
+// "Nikon D850 (14bit-uncompressed)".
+func CameraList() []string {
+	return append([]string(nil), cameraList()...)
+}
+
+// IsSupported reports whether LibRaw supports the file meta was read from:
+// DNGs are decoded generically, whatever camera wrote them, and other
+// files need their camera in the supported list (see IsCameraSupported).
+func IsSupported(meta metadata.ImgMetadata) bool {
+	return meta.IData.DngVersion != 0 || IsCameraSupported(meta.IData.Make, meta.IData.Model)
+}
+
+// IsCameraSupported reports whether a camera, given by the make and model
+// LibRaw reports in LibRawIData, is in the supported camera list. Entries
+// are compared case-insensitively, ignoring variants in parentheses. DNGs
+// from cameras missing in the list (phones, drones, converted files) are
+// supported all the same; use IsSupported to check a file.
+func IsCameraSupported(maker, model string) bool {
+	name := strings.ToLower(maker + " " + model)
+	for _, camera := range cameraList() {
+		camera = strings.ToLower(camera)
+		if i := strings.Index(camera, " ("); i >= 0 {
+			camera = camera[:i]
+		}
+		if camera == name {
+			return true
+		}
+	}
+	return false
+}
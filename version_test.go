@@ -0,0 +1,50 @@
+package golibraw
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+func TestVersion(t *testing.T) {
+	v := VersionNumber()
+	if v < MakeVersion(0, 21, 0) {
+		t.Errorf("LibRaw %v is older than supported", v)
+	}
+	if !strings.HasPrefix(Version(), v.String()) {
+		t.Errorf("Version() = %q does not start with %q", Version(), v)
+	}
+	t.Logf("LibRaw %s, capabilities: %v", Version(), GetCapabilities())
+}
+
+func TestCameraList(t *testing.T) {
+	cameras := CameraList()
+	if len(cameras) == 0 || len(cameras) != CameraCount() {
+		t.Fatalf("CameraList has %d entries, CameraCount is %d", len(cameras), CameraCount())
+	}
+
+	processor := NewProcessor(NewProcessorOptions())
+	for _, path := range getAllFilesInTestDir() {
+		_, meta, err := processor.UnpackRaw(path)
+		if err != nil {
+			t.Fatalf("UnpackRaw failed: %v", err)
+		}
+		if !IsSupported(meta) {
+			t.Errorf("camera of '%s' (%s %s) not in the supported list", path, meta.IData.Make, meta.IData.Model)
+		}
+	}
+
+	if IsCameraSupported("Nonexistent", "Camera 1") {
+		t.Error("unknown camera reported as supported")
+	}
+	var meta metadata.ImgMetadata
+	meta.IData.Make, meta.IData.Model = "Nonexistent", "Phone 1"
+	if IsSupported(meta) {
+		t.Error("unknown camera reported as supported")
+	}
+	meta.IData.DngVersion = 0x01040000
+	if !IsSupported(meta) {
+		t.Error("DNG of an unknown camera reported as unsupported")
+	}
+}
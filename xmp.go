@@ -0,0 +1,106 @@
+package golibraw
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/stmtc233/go-libraw/pkg/icc"
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+// exifToFlip converts an EXIF orientation to a LibRaw flip code, the
+// inverse of LibRawSizes.ExifOrientation.
+var exifToFlip = [9]Flip{1: FlipNone, 2: FlipHorizontal, 3: FlipRotate180, 4: FlipVertical, 5: FlipTranspose, 6: FlipRotate90, 7: FlipTransverse, 8: FlipRotate270}
+
+// ErrRotatedCrop is returned by ApplyXMP for a straightened crop, which
+// Cropbox cannot express.
+var ErrRotatedCrop = errors.New("rotated crops are not supported")
+
+// ApplyXMP maps develop settings read from a sidecar onto the options, so
+// a render matches what the photographer last saw:
+//
+//   - the crop becomes Cropbox. A crop with an angle is left out and
+//     ApplyXMP returns ErrRotatedCrop after applying everything else;
+//   - As Shot and Auto white balance select UseCameraWb and UseAutoWb, a
+//     custom temperature and tint is converted to UserMul through the
+//     camera matrix of meta (and ignored if the file has none);
+//   - the exposure becomes ExpShift with ExpCorrect set, clamped to the
+//     -2 to +3 EV LibRaw supports;
+//   - the orientation becomes UserFlip.
+//
+// meta is the metadata of the RAW file the sidecar belongs to, as read by
+// Identify or UnpackRaw: the crop is scaled by Sizes.Width and Height,
+// which must be the full size, unrotated visible area that Cropbox refers
+// to. The metadata of a processed image may be halved, stretched to square
+// pixels or rotated, and does not fit.
+func (opts *ProcessorOptions) ApplyXMP(s *metadata.DevelopSettings, meta *metadata.ImgMetadata) error {
+	var err error
+	if c := s.Crop; c != nil && c.Angle != 0 {
+		err = fmt.Errorf("crop angle %g: %w", c.Angle, ErrRotatedCrop)
+	} else if c != nil {
+		w, h := float64(meta.Sizes.Width), float64(meta.Sizes.Height)
+		x, y := math.Round(c.Left*w), math.Round(c.Top*h)
+		opts.Cropbox = Box{
+			X1: uint(x),
+			Y1: uint(y),
+			X2: uint(math.Round(c.Right*w) - x),
+			Y2: uint(math.Round(c.Bottom*h) - y),
+		}
+	}
+
+	switch s.WhiteBalance {
+	case "":
+	case metadata.WhiteBalanceAsShot:
+		opts.UseCameraWb, opts.UseAutoWb = true, false
+	case metadata.WhiteBalanceAuto:
+		opts.UseCameraWb, opts.UseAutoWb = false, true
+	default:
+		if mul, ok := temperatureToMul(s.Temperature, s.Tint, &meta.Color, meta.IData.Colors); ok {
+			opts.UseCameraWb, opts.UseAutoWb = false, false
+			opts.UserMul = mul
+		}
+	}
+
+	if s.Exposure != nil {
+		opts.ExpCorrect = true
+		opts.ExpShift = float32(math.Pow(2, math.Max(-2, math.Min(3, *s.Exposure))))
+	}
+
+	if s.Orientation != 0 {
+		opts.UserFlip = exifToFlip[s.Orientation]
+	}
+	return err
+}
+
+// temperatureToMul computes the white balance multipliers that make the
+// illuminant of a temperature/tint setting neutral, using the XYZ to
+// camera matrix of the file. This is an approximation of Camera Raw, which
+// interpolates between two calibrated matrices.
+func temperatureToMul(kelvin, tint float64, color *metadata.ColorData, colors int) ([4]float32, bool) {
+	var mul [4]float32
+	if kelvin <= 0 || colors < 3 || colors > 4 {
+		return mul, false
+	}
+
+	white := icc.Temperature(kelvin, tint).XYZ()
+	for c := range colors {
+		var neutral float64
+		for j := range 3 {
+			neutral += float64(color.CamXYZ[c][j]) * white[j]
+		}
+		if neutral <= 0 {
+			return mul, false
+		}
+		mul[c] = float32(1 / neutral)
+	}
+	if colors == 3 {
+		mul[3] = mul[1]
+	}
+
+	g := mul[1]
+	for c := range mul {
+		mul[c] /= g
+	}
+	return mul, true
+}
@@ -0,0 +1,34 @@
+package golibraw
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stmtc233/go-libraw/pkg/metadata"
+)
+
+func TestApplyXMPCrop(t *testing.T) {
+	meta := &metadata.ImgMetadata{Sizes: metadata.LibRawSizes{Width: 6000, Height: 4000}}
+	crop := &metadata.CropRect{Top: 0.1, Left: 0.05, Bottom: 0.9, Right: 0.95}
+	ev := 1.0
+
+	opts := NewProcessorOptions()
+	if err := opts.ApplyXMP(&metadata.DevelopSettings{Crop: crop}, meta); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Box{X1: 300, Y1: 400, X2: 5400, Y2: 3200}); opts.Cropbox != want {
+		t.Errorf("Cropbox = %+v, want %+v", opts.Cropbox, want)
+	}
+
+	// a straightened crop is reported, not applied as an upright one
+	rotated := *crop
+	rotated.Angle = 2.5
+	opts = NewProcessorOptions()
+	err := opts.ApplyXMP(&metadata.DevelopSettings{Crop: &rotated, Exposure: &ev}, meta)
+	if !errors.Is(err, ErrRotatedCrop) {
+		t.Errorf("err = %v, want ErrRotatedCrop", err)
+	}
+	if opts.Cropbox != (Box{}) || !opts.ExpCorrect || opts.ExpShift != 2 {
+		t.Errorf("Cropbox = %+v, exposure %v %v", opts.Cropbox, opts.ExpCorrect, opts.ExpShift)
+	}
+}